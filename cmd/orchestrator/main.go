@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	config "github.com/bulbosaur/calculator-with-authorization/config"
+	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
 	orchestratorGRPC "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/grpc"
 	orchestratorHTTP "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http"
 	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
@@ -17,15 +20,61 @@ func main() {
 
 	config.Init()
 
-	db, err := repository.InitDB(viper.GetString("DATABASE_PATH"))
+	driver := viper.GetString("DATABASE_DRIVER")
+	dsn := viper.GetString("DATABASE_URL")
+	if dsn == "" {
+		dsn = viper.GetString("DATABASE_PATH")
+	}
+
+	db, err := repository.Open(driver, dsn)
 	if err != nil {
 		log.Fatalf("failed to init DB; %v", err)
 	}
 
 	ExprRepo := repository.NewExpressionModel(db)
+	ExprRepo.AgingInterval = time.Duration(viper.GetInt("priority.AGING_INTERVAL_S")) * time.Second
+	ExprRepo.FIFODispatch = !viper.GetBool("scheduler.CRITICAL_PATH_FIRST")
+	if viper.GetBool("cache.ENABLED") {
+		ExprRepo.ResultCacheTTL = time.Duration(viper.GetInt("cache.TTL_S")) * time.Second
+	}
 
 	defer db.Close()
 
+	if err := orchestrator.PromoteAdmin(ExprRepo, viper.GetString("admin.LOGIN")); err != nil {
+		log.Fatalf("failed to promote admin: %v", err)
+	}
+
+	instanceID := viper.GetString("ha.INSTANCE_ID")
+	if instanceID == "" {
+		instanceID = orchestrator.DefaultInstanceID()
+	}
+
+	elector := &orchestrator.LeaderElector{
+		Repo:          ExprRepo,
+		ID:            instanceID,
+		TTL:           time.Duration(viper.GetInt("ha.LEASE_TTL_MS")) * time.Millisecond,
+		RenewInterval: time.Duration(viper.GetInt("ha.RENEW_INTERVAL_MS")) * time.Millisecond,
+	}
+
+	webhooks := &orchestrator.WebhookDispatcher{
+		Repo:        ExprRepo,
+		Client:      orchestrator.NewWebhookClient(time.Duration(viper.GetInt("webhook.TIMEOUT_MS")) * time.Millisecond),
+		Secret:      viper.GetString("webhook.SECRET"),
+		MaxAttempts: viper.GetInt("webhook.MAX_ATTEMPTS"),
+		Backoff:     time.Duration(viper.GetInt("webhook.BACKOFF_MS")) * time.Millisecond,
+		MaxBackoff:  time.Duration(viper.GetInt("webhook.MAX_BACKOFF_MS")) * time.Millisecond,
+	}
+
+	go elector.Run(context.Background(), func(ctx context.Context) {
+		orchestrator.RunDeadlineReaper(
+			ctx,
+			ExprRepo,
+			time.Duration(viper.GetInt("expression.DEADLINE_CHECK_INTERVAL_MS"))*time.Millisecond,
+		)
+	}, func(ctx context.Context) {
+		webhooks.Run(ctx, time.Duration(viper.GetInt("webhook.POLL_INTERVAL_MS"))*time.Millisecond)
+	})
+
 	go orchestratorHTTP.RunHTTPOrchestrator(ExprRepo)
 	err = orchestratorGRPC.RunGRPCOrchestrator(ExprRepo)
 
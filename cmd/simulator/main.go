@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/config"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/bulbosaur/calculator-with-authorization/internal/simulator"
+	"github.com/spf13/viper"
+
+	_ "modernc.org/sqlite"
+)
+
+// Сценарий читается построчно: либо просто выражение (отправляется в начале симуляции пользователем 1),
+// либо "at_ms,user_id,priority,expression". Пустые строки и строки, начинающиеся с #, пропускаются
+func main() {
+	config.Init()
+
+	input := flag.String("input", "", "file with expressions, one per line (stdin by default)")
+	agents := flag.Int("agents", 1, "number of simulated agents")
+	workers := flag.Int("workers", viper.GetInt("worker.COMPUTING_POWER"), "workers per agent")
+	fifo := flag.Bool("fifo", !viper.GetBool("scheduler.CRITICAL_PATH_FIRST"), "dispatch tasks in creation order instead of critical path first")
+	verbose := flag.Bool("v", false, "print orchestrator logs")
+	flag.Parse()
+
+	var reader io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			log.Fatalf("failed to open scenario: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	submissions, err := readScenario(reader)
+	if err != nil {
+		log.Fatalf("failed to read scenario: %v", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "calc-simulator")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := repository.InitDB(filepath.Join(tempDir, "simulation.db"))
+	if err != nil {
+		log.Fatalf("failed to init DB; %v", err)
+	}
+	defer db.Close()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	report, err := simulator.Run(db, simulator.Config{
+		Agents:        *agents,
+		Workers:       *workers,
+		FIFODispatch:  *fifo,
+		AgingInterval: time.Duration(viper.GetInt("priority.AGING_INTERVAL_S")) * time.Second,
+	}, submissions)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalf("simulation failed: %v", err)
+	}
+
+	fmt.Printf("expressions:      %d (rejected %d, failed %d)\n", report.Expressions, report.Rejected, report.Failed)
+	fmt.Printf("tasks:            %d\n", report.Tasks)
+	fmt.Printf("makespan:         %v\n", report.Makespan)
+	fmt.Printf("queue wait:       avg %v, max %v\n", report.AvgQueueWait, report.MaxQueueWait)
+	fmt.Printf("latency:          avg %v\n", report.AvgLatency)
+	fmt.Printf("utilisation:      %.1f%%\n", report.Utilisation*100)
+}
+
+func readScenario(reader io.Reader) ([]simulator.Submission, error) {
+	var submissions []simulator.Submission
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.Split(text, ",")
+		if len(parts) == 1 {
+			submissions = append(submissions, simulator.Submission{UserID: 1, Expression: text})
+			continue
+		}
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("line %d: expected \"expression\" or \"at_ms,user_id,priority,expression\"", line)
+		}
+
+		var fields [3]int
+		for i := range fields {
+			value, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			fields[i] = value
+		}
+
+		submissions = append(submissions, simulator.Submission{
+			At:         time.Duration(fields[0]) * time.Millisecond,
+			UserID:     fields[1],
+			Priority:   fields[2],
+			Expression: strings.TrimSpace(parts[3]),
+		})
+	}
+
+	return submissions, scanner.Err()
+}
@@ -25,6 +25,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 100, viper.GetInt("duration.TIME_DIVISIONS_MS"))
 
 	assert.Equal(t, "./db/calc.db", viper.GetString("DATABASE_PATH"))
+	assert.Equal(t, "sqlite", viper.GetString("DATABASE_DRIVER"))
 	assert.Equal(t, 5, viper.GetInt("worker.COMPUTING_POWER"))
 
 	assert.Equal(t, "your_secret_key_here", viper.GetString("jwt.secret_key"))
@@ -22,11 +22,52 @@ func Init() {
 	viper.SetDefault("duration.TIME_MULTIPLICATIONS_MS", 100)
 	viper.SetDefault("duration.TIME_DIVISIONS_MS", 100)
 	viper.SetDefault("DATABASE_PATH", "./db/calc.db")
+	viper.SetDefault("DATABASE_DRIVER", "sqlite")
+	viper.SetDefault("DATABASE_URL", "")
 	viper.SetDefault("worker.COMPUTING_POWER", 5)
+	viper.SetDefault("worker.USE_SESSION", false)
+	viper.SetDefault("worker.AGENT_ID", "")
+	viper.SetDefault("worker.OPERATIONS", "+,-,*,/")
+
+	viper.SetDefault("agent.HEARTBEAT_INTERVAL_MS", 1000)
+	viper.SetDefault("agent.HEARTBEAT_TIMEOUT_MS", 5000)
 
 	viper.SetDefault("jwt.secret_key", "your_secret_key_here")
 	viper.SetDefault("jwt.token_duration", 24)
 
+	viper.SetDefault("admin.LOGIN", "")
+
+	viper.SetDefault("priority.USER_MAX", 5)
+	viper.SetDefault("priority.ADMIN_MAX", 10)
+	viper.SetDefault("priority.AGING_INTERVAL_S", 30)
+
+	viper.SetDefault("scheduler.CRITICAL_PATH_FIRST", true)
+
+	viper.SetDefault("quota.MAX_ACTIVE_EXPRESSIONS", 0)
+	viper.SetDefault("quota.MAX_TASKS_PER_EXPRESSION", 0)
+	viper.SetDefault("quota.MAX_QUEUED_TASKS", 0)
+
+	viper.SetDefault("backpressure.MAX_QUEUED_TASKS", 0)
+	viper.SetDefault("backpressure.MAX_RETRY_AFTER_S", 60)
+
+	viper.SetDefault("expression.DEFAULT_TIMEOUT_S", 3600)
+	viper.SetDefault("expression.DEADLINE_CHECK_INTERVAL_MS", 1000)
+
+	viper.SetDefault("cache.ENABLED", false)
+	viper.SetDefault("cache.TTL_S", 3600)
+	viper.SetDefault("cache.PRECISION_MODE", "float64")
+
+	viper.SetDefault("webhook.SECRET", "")
+	viper.SetDefault("webhook.MAX_ATTEMPTS", 5)
+	viper.SetDefault("webhook.BACKOFF_MS", 1000)
+	viper.SetDefault("webhook.MAX_BACKOFF_MS", 60000)
+	viper.SetDefault("webhook.TIMEOUT_MS", 5000)
+	viper.SetDefault("webhook.POLL_INTERVAL_MS", 1000)
+
+	viper.SetDefault("ha.INSTANCE_ID", "")
+	viper.SetDefault("ha.LEASE_TTL_MS", 10000)
+	viper.SetDefault("ha.RENEW_INTERVAL_MS", 3000)
+
 	viper.SetConfigName(".env")
 	viper.SetConfigType("env")
 	viper.AddConfigPath("./config")
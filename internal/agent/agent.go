@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
@@ -16,10 +19,29 @@ import (
 // Workers - переменная, в которой хранится количество одновременно работающих воркеров
 var Workers int
 
+// Version - версия агента, которую он сообщает оркестратору при регистрации
+var Version = "dev"
+
+// supportedOperations - операции, которые умеет выполнять агент
+var supportedOperations = []string{"+", "-", "*", "/"}
+
 // GRPCAgent - gRPC-клиент для взаимодействия с оркестратором вычислений
 type GRPCAgent struct {
 	Client proto.TaskServiceClient
 	Conn   *grpc.ClientConn
+
+	// ID - идентификатор агента, под которым он зарегистрирован в оркестраторе.
+	// Пустой ID означает, что агент работает без регистрации
+	ID string
+
+	// Operations - операции, которые агент берет в работу. Пустой список означает все поддерживаемые операции
+	Operations []string
+
+	// UseSession включает работу через двунаправленную сессию с heartbeat'ами вместо потока StreamTasks
+	UseSession bool
+
+	durationsMu sync.Mutex
+	durations   map[string]int
 }
 
 func newGRPCAgent() (*GRPCAgent, error) {
@@ -68,22 +90,90 @@ func RunAgent() {
 		Workers = 1
 	}
 
-	for i := 1; i <= Workers; i++ {
-		log.Printf("Starting worker %d", i)
-		go agent.Worker(i)
+	agent.UseSession = viper.GetBool("worker.USE_SESSION")
+
+	agent.Operations, err = parseOperations(viper.GetString("worker.OPERATIONS"))
+	if err != nil {
+		log.Printf("Failed to create agent: %v", err)
+		return
 	}
 
-	log.Printf("Starting %d workers", Workers)
+	agent.ID = viper.GetString("worker.AGENT_ID")
+	if agent.ID == "" {
+		agent.ID = defaultAgentID()
+	}
+
+	ctx := context.Background()
+	go agent.KeepAlive(ctx)
+
+	log.Printf("Starting worker %s with capacity %d", agent.ID, Workers)
+	go agent.Worker(ctx, 1)
 
 	select {}
 }
 
+// parseOperations разбирает список операций через запятую и отбрасывает те, которые агент не умеет выполнять.
+// Если список не пуст, но ни одна операция в нем не поддерживается, возвращает ошибку: иначе агент молча брал бы все операции
+func parseOperations(list string) ([]string, error) {
+	var (
+		operations []string
+		ignored    []string
+	)
+	for _, operation := range strings.Split(list, ",") {
+		operation = strings.TrimSpace(operation)
+		if operation == "" {
+			continue
+		}
+		if !slices.Contains(supportedOperations, operation) {
+			log.Printf("operation %q is not supported by this agent, ignoring", operation)
+			ignored = append(ignored, operation)
+			continue
+		}
+		operations = append(operations, operation)
+	}
+
+	if len(operations) == 0 && len(ignored) > 0 {
+		return nil, fmt.Errorf("none of the operations %q is supported, expected some of %q",
+			strings.Join(ignored, ","), strings.Join(supportedOperations, ","))
+	}
+
+	return operations, nil
+}
+
+// operations возвращает операции, которые агент сообщает оркестратору
+func (a *GRPCAgent) operations() []string {
+	if len(a.Operations) == 0 {
+		return supportedOperations
+	}
+
+	return a.Operations
+}
+
 func (a *GRPCAgent) getTask(ctx context.Context) (*models.Task, error) {
-	resp, err := a.Client.ReceiveTask(ctx, &proto.GetTaskRequest{})
+	resp, err := a.Client.ReceiveTask(ctx, &proto.GetTaskRequest{AgentId: a.ID, Operations: a.operations()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
+	return taskFromProto(resp), nil
+}
+
+// getTasks запрашивает у оркестратора до capacity готовых тасок за один вызов
+func (a *GRPCAgent) getTasks(ctx context.Context, capacity int) ([]*models.Task, error) {
+	resp, err := a.Client.ReceiveTasks(ctx, &proto.ReceiveTasksRequest{Capacity: int32(capacity), AgentId: a.ID, Operations: a.operations()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	tasks := make([]*models.Task, 0, len(resp.Tasks))
+	for _, t := range resp.Tasks {
+		tasks = append(tasks, taskFromProto(t))
+	}
+
+	return tasks, nil
+}
+
+func taskFromProto(resp *proto.Task) *models.Task {
 	task := &models.Task{
 		ID:           int(resp.Id),
 		ExpressionID: int(resp.ExpressionId),
@@ -94,12 +184,17 @@ func (a *GRPCAgent) getTask(ctx context.Context) (*models.Task, error) {
 		Operation:    resp.Operation,
 	}
 
+	if resp.TimeoutMs > 0 {
+		deadline := time.Now().Add(time.Duration(resp.TimeoutMs) * time.Millisecond)
+		task.Deadline = &deadline
+	}
+
 	if task.ID != 0 {
 		log.Printf("Received task: ID=%d, Arg1=%f, Arg2=%f, PrevTaskID1=%d, PrevTaskID2=%d, Operation=%s",
 			task.ID, task.Arg1, task.Arg2, task.PrevTaskID1, task.PrevTaskID2, task.Operation)
 	}
 
-	return task, nil
+	return task
 }
 
 func (a *GRPCAgent) executeTask(ctx context.Context, task *models.Task) (float64, string, error) {
@@ -116,18 +211,32 @@ func (a *GRPCAgent) executeTask(ctx context.Context, task *models.Task) (float64
 		return 0, "", fmt.Errorf("invalid operation: operation is empty")
 	}
 
+	if task.Deadline != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, *task.Deadline)
+		defer cancel()
+	}
+
 	switch task.Operation {
 	case "+":
-		time.Sleep(time.Duration(viper.GetInt("duration.TIME_ADDITION_MS")) * time.Millisecond)
+		if err := wait(ctx, a.operationDuration("+", "duration.TIME_ADDITION_MS")); err != nil {
+			return 0, "", err
+		}
 		return arg1 + arg2, "", nil
 	case "-":
-		time.Sleep(time.Duration(viper.GetInt("duration.TIME_SUBTRACTION_MS")) * time.Millisecond)
+		if err := wait(ctx, a.operationDuration("-", "duration.TIME_SUBTRACTION_MS")); err != nil {
+			return 0, "", err
+		}
 		return arg1 - arg2, "", nil
 	case "*":
-		time.Sleep(time.Duration(viper.GetInt("duration.TIME_MULTIPLICATIONS_MS")) * time.Millisecond)
+		if err := wait(ctx, a.operationDuration("*", "duration.TIME_MULTIPLICATIONS_MS")); err != nil {
+			return 0, "", err
+		}
 		return arg1 * arg2, "", nil
 	case "/":
-		time.Sleep(time.Duration(viper.GetInt("duration.TIME_DIVISIONS_MS")) * time.Millisecond)
+		if err := wait(ctx, a.operationDuration("/", "duration.TIME_DIVISIONS_MS")); err != nil {
+			return 0, "", err
+		}
 		if arg2 == 0 {
 			return 0, models.ErrorDivisionByZero.Error(), nil
 		}
@@ -137,6 +246,19 @@ func (a *GRPCAgent) executeTask(ctx context.Context, task *models.Task) (float64
 	}
 }
 
+// wait имитирует длительность операции и прерывается, если у таски закончилось время
+func wait(ctx context.Context, ms int) error {
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("task abandoned: %w", ctx.Err())
+	}
+}
+
 func (a *GRPCAgent) sendResult(ctx context.Context, taskID int, result float64, errorMessage string) error {
 	Mu.Lock()
 	defer Mu.Unlock()
@@ -153,3 +275,14 @@ func (a *GRPCAgent) sendResult(ctx context.Context, taskID int, result float64,
 
 	return nil
 }
+
+// sendResults отправляет оркестратору результаты нескольких тасок за один вызов
+// и возвращает ID тасок, результаты которых оркестратор не принял
+func (a *GRPCAgent) sendResults(ctx context.Context, results []*proto.SubmitTaskResultRequest) ([]int32, error) {
+	resp, err := a.Client.SubmitTaskResults(ctx, &proto.SubmitTaskResultsRequest{Results: results})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send results: %v", err)
+	}
+
+	return resp.FailedTaskIds, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,7 +13,9 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -21,6 +24,11 @@ type testOrchestratorServer struct {
 	tasks    map[int32]*proto.Task
 	results  map[int32]float64
 	received chan *proto.SubmitTaskResultRequest
+
+	heartbeats chan *proto.Heartbeat
+
+	registrations   chan *proto.AgentInfo
+	agentHeartbeats atomic.Int32
 }
 
 func (s *testOrchestratorServer) ReceiveTask(ctx context.Context, req *proto.GetTaskRequest) (*proto.Task, error) {
@@ -35,6 +43,79 @@ func (s *testOrchestratorServer) SubmitTaskResult(ctx context.Context, req *prot
 	return &proto.SubmitTaskResultResponse{}, nil
 }
 
+func (s *testOrchestratorServer) ReceiveTasks(ctx context.Context, req *proto.ReceiveTasksRequest) (*proto.TaskBatch, error) {
+	batch := &proto.TaskBatch{}
+	for _, task := range s.tasks {
+		if len(batch.Tasks) == int(req.Capacity) {
+			break
+		}
+		batch.Tasks = append(batch.Tasks, task)
+	}
+	return batch, nil
+}
+
+func (s *testOrchestratorServer) StreamTasks(req *proto.ReceiveTasksRequest, stream grpc.ServerStreamingServer[proto.Task]) error {
+	for _, task := range s.tasks {
+		if err := stream.Send(task); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func (s *testOrchestratorServer) AgentSession(stream grpc.BidiStreamingServer[proto.AgentMessage, proto.OrchestratorMessage]) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+
+	err := stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Config{
+		Config: &proto.AgentConfig{HeartbeatIntervalMs: 50, OperationDurationMs: map[string]int64{"+": 10}},
+	}})
+	if err != nil {
+		return err
+	}
+
+	for _, task := range s.tasks {
+		if err := stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Assignment{Assignment: task}}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if result := msg.GetResult(); result != nil {
+			s.received <- result
+		}
+		if msg.GetHeartbeat() != nil {
+			s.heartbeats <- msg.GetHeartbeat()
+		}
+	}
+}
+
+func (s *testOrchestratorServer) SubmitTaskResults(ctx context.Context, req *proto.SubmitTaskResultsRequest) (*proto.SubmitTaskResultsResponse, error) {
+	for _, result := range req.Results {
+		s.received <- result
+	}
+	return &proto.SubmitTaskResultsResponse{Accepted: int32(len(req.Results))}, nil
+}
+
+func (s *testOrchestratorServer) RegisterAgent(ctx context.Context, req *proto.AgentInfo) (*proto.RegisterAgentResponse, error) {
+	s.registrations <- req
+	return &proto.RegisterAgentResponse{HeartbeatIntervalMs: 20}, nil
+}
+
+// AgentHeartbeat на первый heartbeat отвечает NotFound, имитируя перезапуск оркестратора
+func (s *testOrchestratorServer) AgentHeartbeat(ctx context.Context, req *proto.AgentHeartbeatRequest) (*proto.AgentHeartbeatResponse, error) {
+	if s.agentHeartbeats.Add(1) == 1 {
+		return nil, status.Error(codes.NotFound, "agent not found")
+	}
+	return &proto.AgentHeartbeatResponse{}, nil
+}
+
 var lis *bufconn.Listener
 
 func startTestServer() (*grpc.Server, *testOrchestratorServer) {
@@ -44,6 +125,10 @@ func startTestServer() (*grpc.Server, *testOrchestratorServer) {
 		tasks:    make(map[int32]*proto.Task),
 		results:  make(map[int32]float64),
 		received: make(chan *proto.SubmitTaskResultRequest, 10),
+
+		heartbeats: make(chan *proto.Heartbeat, 100),
+
+		registrations: make(chan *proto.AgentInfo, 10),
 	}
 	proto.RegisterTaskServiceServer(srv, testServer)
 	go func() {
@@ -164,6 +249,20 @@ func (m *mockTaskServiceClient) ReceiveTask(ctx context.Context, req *proto.GetT
 	return &proto.Task{}, nil
 }
 
+func (m *mockTaskServiceClient) ReceiveTasks(ctx context.Context, req *proto.ReceiveTasksRequest, opts ...grpc.CallOption) (*proto.TaskBatch, error) {
+	if m.receiveTaskError {
+		return nil, fmt.Errorf("mock receive error")
+	}
+	return &proto.TaskBatch{}, nil
+}
+
+func (m *mockTaskServiceClient) StreamTasks(ctx context.Context, req *proto.ReceiveTasksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[proto.Task], error) {
+	if m.receiveTaskError {
+		return nil, fmt.Errorf("mock receive error")
+	}
+	return nil, status.Error(codes.Unimplemented, "mock streaming is not supported")
+}
+
 func TestGetTask_Error(t *testing.T) {
 	agent := &GRPCAgent{
 		Client: &mockTaskServiceClient{receiveTaskError: true},
@@ -176,6 +275,10 @@ func (m *mockTaskServiceClient) SubmitTaskResult(ctx context.Context, req *proto
 	return nil, fmt.Errorf("mock submit error")
 }
 
+func (m *mockTaskServiceClient) SubmitTaskResults(ctx context.Context, req *proto.SubmitTaskResultsRequest, opts ...grpc.CallOption) (*proto.SubmitTaskResultsResponse, error) {
+	return nil, fmt.Errorf("mock submit error")
+}
+
 func TestSendResult_Error(t *testing.T) {
 	agent := &GRPCAgent{
 		Client: &mockTaskServiceClient{},
@@ -283,3 +386,70 @@ func TestSendResult_WithErrorMessage(t *testing.T) {
 		t.Fatal("Timeout waiting for result")
 	}
 }
+
+func TestExecuteTask_DeadlineExceeded(t *testing.T) {
+	viper.Set("duration.TIME_ADDITION_MS", 1000)
+	defer viper.Set("duration.TIME_ADDITION_MS", 100)
+
+	agent := &GRPCAgent{}
+	deadline := time.Now().Add(50 * time.Millisecond)
+	task := &models.Task{ID: 1, Operation: "+", Arg1: 1, Arg2: 2, Deadline: &deadline}
+
+	start := time.Now()
+	_, _, err := agent.executeTask(context.Background(), task)
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start).Milliseconds(), int64(500), "execution must stop at the task deadline")
+}
+
+func TestKeepAlive_RegistersAgain(t *testing.T) {
+	srv, testServer := startTestServer()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Connection error: %v", err)
+	}
+	defer conn.Close()
+
+	Workers = 3
+	agent := &GRPCAgent{Client: proto.NewTaskServiceClient(conn), Conn: conn, ID: "agent-1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.KeepAlive(ctx)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case info := <-testServer.registrations:
+			assert.Equal(t, "agent-1", info.AgentId)
+			assert.Equal(t, int32(3), info.Workers)
+			assert.Equal(t, Version, info.Version)
+			assert.ElementsMatch(t, []string{"+", "-", "*", "/"}, info.Operations)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("registration %d was not received", i+1)
+		}
+	}
+}
+
+func TestParseOperations(t *testing.T) {
+	operations, err := parseOperations(" +, *,,^")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+", "*"}, operations)
+
+	operations, err = parseOperations("")
+	assert.NoError(t, err)
+	assert.Nil(t, operations)
+
+	_, err = parseOperations("^, %")
+	assert.Error(t, err, "list without a single supported operation must be rejected")
+
+	agent := &GRPCAgent{}
+	assert.Equal(t, supportedOperations, agent.operations(), "agent without a list executes every supported operation")
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultHeartbeatInterval - как часто агент сообщает о себе, пока оркестратор не прислал свой интервал
+const defaultHeartbeatInterval = 1 * time.Second
+
+// defaultAgentID собирает ID агента из имени хоста и PID процесса
+func defaultAgentID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "agent"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// register регистрирует агента в оркестраторе и возвращает интервал, с которым нужно присылать heartbeat
+func (a *GRPCAgent) register(ctx context.Context) (time.Duration, error) {
+	hostname, _ := os.Hostname()
+
+	resp, err := a.Client.RegisterAgent(ctx, &proto.AgentInfo{
+		AgentId:    a.ID,
+		Hostname:   hostname,
+		Version:    Version,
+		Workers:    int32(Workers),
+		Operations: a.operations(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to register agent: %w", err)
+	}
+
+	interval := time.Duration(resp.HeartbeatIntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+
+	return interval, nil
+}
+
+// KeepAlive регистрирует агента и затем периодически сообщает оркестратору, что агент на связи.
+// Если оркестратор забыл агента (например, после перезапуска), агент регистрируется заново.
+// Завершается при отмене ctx
+func (a *GRPCAgent) KeepAlive(ctx context.Context) {
+	if a.ID == "" {
+		return
+	}
+
+	var (
+		interval   = defaultHeartbeatInterval
+		registered bool
+	)
+
+	for ctx.Err() == nil {
+		if !registered {
+			next, err := a.register(ctx)
+			if status.Code(err) == codes.Unimplemented {
+				log.Printf("agent %s: registry is not supported by orchestrator", a.ID)
+				return
+			}
+			if err != nil {
+				log.Printf("agent %s: %v", a.ID, err)
+				sleep(ctx, pollInterval)
+				continue
+			}
+
+			interval = next
+			registered = true
+			log.Printf("agent %s registered, heartbeat every %v", a.ID, interval)
+		}
+
+		sleep(ctx, interval)
+		if ctx.Err() != nil {
+			return
+		}
+
+		_, err := a.Client.AgentHeartbeat(ctx, &proto.AgentHeartbeatRequest{AgentId: a.ID})
+		switch {
+		case status.Code(err) == codes.NotFound:
+			registered = false
+		case err != nil:
+			log.Printf("agent %s: heartbeat error: %v", a.ID, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"github.com/spf13/viper"
+)
+
+// sessionTasks работает с оркестратором через двунаправленную сессию: получает таски, отмены и настройки,
+// отправляет heartbeat'ы и результаты. Возвращается, когда сессия обрывается
+func (a *GRPCAgent) sessionTasks(ctx context.Context, id, capacity int, slots chan struct{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := a.Client.AgentSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = stream.Send(&proto.AgentMessage{Payload: &proto.AgentMessage_Hello{
+		Hello: &proto.AgentHello{Capacity: int32(capacity), AgentId: a.ID, Operations: a.operations()},
+	}})
+	if err != nil {
+		return err
+	}
+
+	results := make(chan *proto.SubmitTaskResultRequest, capacity)
+	heartbeat := make(chan time.Duration, 1)
+	sendErr := make(chan error, 1)
+
+	go func() {
+		sendErr <- a.sendSession(ctx, stream, results, heartbeat)
+	}()
+
+	var (
+		runningMu sync.Mutex
+		running   = make(map[int32]context.CancelFunc)
+	)
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			select {
+			case sendErr := <-sendErr:
+				if sendErr != nil {
+					return sendErr
+				}
+			default:
+			}
+			return err
+		}
+
+		switch payload := msg.Payload.(type) {
+		case *proto.OrchestratorMessage_Assignment:
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			task := taskFromProto(payload.Assignment)
+			taskCtx, cancelTask := context.WithCancel(ctx)
+
+			runningMu.Lock()
+			running[payload.Assignment.Id] = cancelTask
+			runningMu.Unlock()
+
+			go func() {
+				defer func() {
+					runningMu.Lock()
+					delete(running, int32(task.ID))
+					runningMu.Unlock()
+					cancelTask()
+				}()
+				a.runTask(taskCtx, id, task, slots, results)
+			}()
+
+		case *proto.OrchestratorMessage_Cancellation:
+			runningMu.Lock()
+			cancelTask, ok := running[payload.Cancellation.TaskId]
+			runningMu.Unlock()
+
+			if ok {
+				log.Printf("Worker %d: task ID-%d cancelled by orchestrator: %s", id, payload.Cancellation.TaskId, payload.Cancellation.Reason)
+				cancelTask()
+			}
+
+		case *proto.OrchestratorMessage_Config:
+			a.applyConfig(payload.Config)
+			if payload.Config.HeartbeatIntervalMs > 0 {
+				select {
+				case <-heartbeat:
+				default:
+				}
+				heartbeat <- time.Duration(payload.Config.HeartbeatIntervalMs) * time.Millisecond
+			}
+
+		case *proto.OrchestratorMessage_Heartbeat:
+		}
+	}
+}
+
+// sendSession - единственный отправитель в сессию: пересылает результаты тасок и шлет heartbeat'ы
+func (a *GRPCAgent) sendSession(
+	ctx context.Context,
+	stream proto.TaskService_AgentSessionClient,
+	results <-chan *proto.SubmitTaskResultRequest,
+	heartbeat <-chan time.Duration,
+) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var msg *proto.AgentMessage
+
+		select {
+		case result := <-results:
+			msg = &proto.AgentMessage{Payload: &proto.AgentMessage_Result{Result: result}}
+		case interval := <-heartbeat:
+			ticker.Reset(interval)
+			continue
+		case now := <-ticker.C:
+			msg = &proto.AgentMessage{Payload: &proto.AgentMessage_Heartbeat{
+				Heartbeat: &proto.Heartbeat{SentAtMs: now.UnixMilli()},
+			}}
+		case <-ctx.Done():
+			return nil
+		}
+
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// applyConfig применяет присланные оркестратором длительности операций
+func (a *GRPCAgent) applyConfig(cfg *proto.AgentConfig) {
+	if len(cfg.OperationDurationMs) == 0 {
+		return
+	}
+
+	a.durationsMu.Lock()
+	defer a.durationsMu.Unlock()
+
+	if a.durations == nil {
+		a.durations = make(map[string]int)
+	}
+	for operation, ms := range cfg.OperationDurationMs {
+		a.durations[operation] = int(ms)
+	}
+}
+
+// operationDuration возвращает длительность операции: присланную оркестратором или из настроек агента
+func (a *GRPCAgent) operationDuration(operation, key string) int {
+	a.durationsMu.Lock()
+	ms, ok := a.durations[operation]
+	a.durationsMu.Unlock()
+
+	if ok {
+		return ms
+	}
+	return viper.GetInt(key)
+}
@@ -5,54 +5,230 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Mu - мьютекс в рамках микросервиса данного агента
 var Mu sync.Mutex
 
-// Worker изолированное выполняет свою задачу по вычислению
-func (a *GRPCAgent) Worker(id int) {
-	sem := make(chan struct{}, Workers)
-	interval := 1 * time.Second
-	ctx := context.Background()
+// Worker выполняет до Workers тасок одновременно. Таски приходят от оркестратора через поток StreamTasks
+// (или через сессию AgentSession, если включен UseSession) сразу, как только становятся готовыми к выполнению.
+// Если оркестратор не поддерживает поток, Worker опрашивает его пакетами через ReceiveTasks.
+// Готовые результаты отправляются обратно пакетами. Worker завершается при отмене ctx
+func (a *GRPCAgent) Worker(ctx context.Context, id int) {
+	capacity := Workers
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	slots := make(chan struct{}, capacity)
+	results := make(chan *proto.SubmitTaskResultRequest, capacity)
+
+	go a.submitResults(ctx, id, results)
+
+	for ctx.Err() == nil {
+		var err error
+		if a.UseSession {
+			err = a.sessionTasks(ctx, id, capacity, slots)
+		} else {
+			err = a.streamTasks(ctx, id, capacity, slots, results)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if status.Code(err) == codes.Unimplemented {
+			log.Printf("worker %d: task streaming is not supported by orchestrator, falling back to polling", id)
+			a.pollTasks(ctx, id, capacity, slots, results)
+			return
+		}
+
+		log.Printf("worker %d: task receiving error: %v", id, err)
+		sleep(ctx, pollInterval)
+	}
+}
+
+// pollInterval - пауза перед повторным запросом, если тасок нет или оркестратор недоступен
+const pollInterval = 1 * time.Second
+
+// streamTasks открывает поток тасок и выполняет все, что в него приходит, пока поток не оборвется
+func (a *GRPCAgent) streamTasks(ctx context.Context, id, capacity int, slots chan struct{}, results chan<- *proto.SubmitTaskResultRequest) error {
+	stream, err := a.Client.StreamTasks(ctx, &proto.ReceiveTasksRequest{Capacity: int32(capacity), AgentId: a.ID, Operations: a.operations()})
+	if err != nil {
+		return err
+	}
 
 	for {
-		sem <- struct{}{}
-		Mu.Lock()
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 
-		task, err := a.getTask(ctx)
+		go a.runTask(ctx, id, taskFromProto(resp), slots, results)
+	}
+}
 
-		Mu.Unlock()
+// pollTasks запрашивает у оркестратора столько тасок, сколько сейчас свободно слотов
+func (a *GRPCAgent) pollTasks(ctx context.Context, id, capacity int, slots chan struct{}, results chan<- *proto.SubmitTaskResultRequest) {
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		free := capacity - len(slots) + 1
 
+		tasks, err := a.getTasks(ctx, free)
 		if err != nil {
+			<-slots
+			if ctx.Err() != nil {
+				return
+			}
 			log.Printf("worker %d: task receiving error: %v", id, err)
-			time.Sleep(interval)
-			<-sem
+			sleep(ctx, pollInterval)
 			continue
 		}
 
-		result, errorMessage, err := a.executeTask(ctx, task)
+		if len(tasks) == 0 {
+			<-slots
+			sleep(ctx, pollInterval)
+			continue
+		}
+
+		for i, task := range tasks {
+			if i > 0 {
+				slots <- struct{}{}
+			}
+			go a.runTask(ctx, id, task, slots, results)
+		}
+	}
+}
+
+// runTask выполняет таску, занявшую слот, и передает результат на отправку
+func (a *GRPCAgent) runTask(ctx context.Context, id int, task *models.Task, slots <-chan struct{}, results chan<- *proto.SubmitTaskResultRequest) {
+	defer func() { <-slots }()
+
+	result, errorMessage, err := a.executeTask(ctx, task)
+	if err != nil {
+		if task.ID != 0 {
+			log.Printf("Worker %d: execution error task ID-%d: %v", id, task.ID, err)
+		} else {
+			log.Printf("Worker %d: received invalid task (ID=0): %v", id, err)
+		}
+		return
+	}
+
+	select {
+	case results <- &proto.SubmitTaskResultRequest{
+		TaskId:       int32(task.ID),
+		Result:       result,
+		ErrorMessage: errorMessage,
+	}:
+	case <-ctx.Done():
+	}
+}
+
+// submitResults собирает готовые результаты в пакеты и отправляет их оркестратору.
+// В пакет попадает все, что успело накопиться к моменту отправки. Результаты, которые не удалось отправить
+// или которые оркестратор не записал, отправляются повторно с растущей паузой, пока не отменен ctx
+func (a *GRPCAgent) submitResults(ctx context.Context, id int, results <-chan *proto.SubmitTaskResultRequest) {
+	var (
+		pending []*proto.SubmitTaskResultRequest
+		backoff time.Duration
+	)
+
+	for {
+		if len(pending) == 0 {
+			select {
+			case result := <-results:
+				pending = append(pending, result)
+			case <-ctx.Done():
+				return
+			}
+		}
+
+	collect:
+		for {
+			select {
+			case result := <-results:
+				pending = append(pending, result)
+			default:
+				break collect
+			}
+		}
+
+		failed, err := a.sendResults(ctx, pending)
 		if err != nil {
-			if task.ID != 0 {
-				log.Printf("Worker %d: execution error task ID-%d: %v", id, task.ID, err)
-			} else {
-				log.Printf("Worker %d: received invalid task (ID=0): %v", id, err)
+			if ctx.Err() != nil {
+				return
 			}
-			time.Sleep(interval)
-			<-sem
+			backoff = nextResultBackoff(backoff)
+			log.Printf("Worker %d: sending error for %d results, retry in %v: %v", id, len(pending), backoff, err)
+			sleep(ctx, backoff)
 			continue
 		}
 
-		if task.ID != 0 {
-			err = a.sendResult(ctx, task.ID, result, errorMessage)
-			if err != nil {
-				log.Printf("Worker %d: sending error task ID-%d: %v", id, task.ID, err)
-			} else {
-				log.Printf("Worker %d: success task ID-%d\nresult: %f", id, task.ID, result)
+		rejected := make(map[int32]bool, len(failed))
+		for _, taskID := range failed {
+			rejected[taskID] = true
+		}
+
+		var retry []*proto.SubmitTaskResultRequest
+		for _, result := range pending {
+			switch {
+			case !rejected[result.TaskId]:
+				log.Printf("Worker %d: success task ID-%d\nresult: %f", id, result.TaskId, result.Result)
+			case result.TaskId <= 0:
+				log.Printf("Worker %d: result of invalid task ID-%d dropped", id, result.TaskId)
+			default:
+				log.Printf("Worker %d: sending error task ID-%d: result rejected by orchestrator", id, result.TaskId)
+				retry = append(retry, result)
 			}
 		}
 
-		<-sem
-		time.Sleep(interval)
+		pending = retry
+		if len(pending) == 0 {
+			backoff = 0
+			continue
+		}
+		backoff = nextResultBackoff(backoff)
+		sleep(ctx, backoff)
+	}
+}
+
+const (
+	// resultRetryBackoff - пауза перед первой повторной отправкой результатов, дальше она удваивается
+	resultRetryBackoff = 200 * time.Millisecond
+
+	// maxResultRetryBackoff - наибольшая пауза между повторными отправками результатов
+	maxResultRetryBackoff = 10 * time.Second
+)
+
+// nextResultBackoff возвращает паузу перед следующей повторной отправкой
+func nextResultBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return resultRetryBackoff
+	}
+	return min(backoff*2, maxResultRetryBackoff)
+}
+
+// sleep ждет interval или отмены ctx
+func sleep(ctx context.Context, interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
 	}
 }
@@ -2,9 +2,11 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -46,8 +48,11 @@ func TestWorker(t *testing.T) {
 		Conn:   conn,
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	Workers = 1
-	go agent.Worker(1)
+	go agent.Worker(ctx, 1)
 
 	select {
 	case req := <-testServer.received:
@@ -58,6 +63,54 @@ func TestWorker(t *testing.T) {
 	}
 }
 
+func TestWorker_ExecutesBatchConcurrently(t *testing.T) {
+	srv, testServer := startTestServer()
+	defer srv.Stop()
+
+	for i := int32(1); i <= 3; i++ {
+		testServer.tasks[i] = &proto.Task{Id: i, Arg1: float64(i), Arg2: 1, Operation: "+"}
+	}
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Connection error: %v", err)
+	}
+	defer conn.Close()
+
+	agent := &GRPCAgent{
+		Client: proto.NewTaskServiceClient(conn),
+		Conn:   conn,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	viper.Set("duration.TIME_ADDITION_MS", 300)
+	defer viper.Set("duration.TIME_ADDITION_MS", 100)
+
+	Workers = 3
+	start := time.Now()
+	go agent.Worker(ctx, 1)
+
+	seen := make(map[int32]bool)
+	for len(seen) < 3 {
+		select {
+		case req := <-testServer.received:
+			seen[req.TaskId] = true
+		case <-time.After(3 * time.Second):
+			t.Fatal("Timeout waiting for results")
+		}
+	}
+
+	assert.Less(t, time.Since(start), 600*time.Millisecond, "tasks of one batch must run concurrently")
+}
+
 func TestExecutionTime(t *testing.T) {
 	agent := &GRPCAgent{}
 	task := &models.Task{
@@ -110,8 +163,11 @@ func TestWorkerErrorHandling(t *testing.T) {
 	log.SetOutput(&logWriter{logs: logChan})
 	defer log.SetOutput(os.Stderr)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	Workers = 1
-	go agent.Worker(1)
+	go agent.Worker(ctx, 1)
 
 	var logMsg string
 	for i := 0; i < 2; i++ {
@@ -165,8 +221,11 @@ func TestWorkerNilTaskHandling(t *testing.T) {
 	log.SetOutput(&logWriter{logs: logChan})
 	defer log.SetOutput(os.Stderr)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	Workers = 1
-	go agent.Worker(1)
+	go agent.Worker(ctx, 1)
 
 	select {
 	case msg := <-logChan:
@@ -184,8 +243,11 @@ func TestWorker_RetryOnFailure(t *testing.T) {
 	log.SetOutput(&logWriter{logs: logChan})
 	defer log.SetOutput(os.Stderr)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	Workers = 1
-	go agent.Worker(1)
+	go agent.Worker(ctx, 1)
 
 	select {
 	case msg := <-logChan:
@@ -194,3 +256,127 @@ func TestWorker_RetryOnFailure(t *testing.T) {
 		t.Fatal("Timeout waiting for retry log")
 	}
 }
+
+func TestWorker_FallsBackToPolling(t *testing.T) {
+	agent := &GRPCAgent{Client: &mockTaskServiceClient{}}
+
+	logChan := make(chan string, 1)
+	log.SetOutput(&logWriter{logs: logChan})
+	defer log.SetOutput(os.Stderr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Workers = 1
+	go agent.Worker(ctx, 1)
+
+	select {
+	case msg := <-logChan:
+		assert.Contains(t, msg, "falling back to polling")
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for fallback log")
+	}
+}
+
+func TestWorker_Session(t *testing.T) {
+	srv, testServer := startTestServer()
+	defer srv.Stop()
+
+	testServer.tasks[1] = &proto.Task{Id: 1, Arg1: 2, Arg2: 3, Operation: "+"}
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Connection error: %v", err)
+	}
+	defer conn.Close()
+
+	agent := &GRPCAgent{
+		Client:     proto.NewTaskServiceClient(conn),
+		Conn:       conn,
+		UseSession: true,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Workers = 1
+	start := time.Now()
+	go agent.Worker(ctx, 1)
+
+	select {
+	case req := <-testServer.received:
+		assert.Equal(t, int32(1), req.TaskId)
+		assert.Equal(t, float64(5), req.Result)
+		assert.Less(t, time.Since(start), 90*time.Millisecond, "operation duration from the session config must be applied")
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for result")
+	}
+
+	select {
+	case <-testServer.heartbeats:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for heartbeat")
+	}
+}
+
+// flakySubmitClient не принимает первую отправку результатов, а во второй отклоняет таску rejectOnce
+type flakySubmitClient struct {
+	proto.TaskServiceClient
+	rejectOnce int32
+
+	mu       sync.Mutex
+	calls    int
+	accepted map[int32]float64
+}
+
+func (c *flakySubmitClient) SubmitTaskResults(ctx context.Context, req *proto.SubmitTaskResultsRequest, opts ...grpc.CallOption) (*proto.SubmitTaskResultsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if c.calls == 1 {
+		return nil, fmt.Errorf("orchestrator unavailable")
+	}
+
+	resp := &proto.SubmitTaskResultsResponse{}
+	for _, result := range req.Results {
+		if c.calls == 2 && result.TaskId == c.rejectOnce {
+			resp.FailedTaskIds = append(resp.FailedTaskIds, result.TaskId)
+			continue
+		}
+		c.accepted[result.TaskId] = result.Result
+		resp.Accepted++
+	}
+	return resp, nil
+}
+
+func TestSubmitResults_RetriesUnsentResults(t *testing.T) {
+	client := &flakySubmitClient{rejectOnce: 2, accepted: make(map[int32]float64)}
+	agent := &GRPCAgent{Client: client}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan *proto.SubmitTaskResultRequest, 2)
+	results <- &proto.SubmitTaskResultRequest{TaskId: 1, Result: 10}
+	results <- &proto.SubmitTaskResultRequest{TaskId: 2, Result: 20}
+
+	go agent.submitResults(ctx, 1, results)
+
+	assert.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.accepted) == 2
+	}, 3*time.Second, 10*time.Millisecond, "results must be resent after a failed submission")
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	assert.Equal(t, map[int32]float64{1: 10, 2: 20}, client.accepted)
+	assert.Equal(t, 3, client.calls)
+}
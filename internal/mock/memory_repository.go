@@ -0,0 +1,438 @@
+package mock
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// MemoryRepository - тестовый дублер хранилища: пользователи, выражения, таски и агенты в памяти процесса.
+// Реализует models.ExpressionRepository и models.AgentRepository, поэтому на нем можно тестировать
+// gRPC сервер и агентов без базы. Оркестратор его не использует и выбрать его в конфиге нельзя:
+// HTTP обработчики работают с *repository.ExpressionModel, а для демонстрации без файла базы
+// есть драйвер repository.DriverSQLiteMemory.
+//
+// Пауза и остановка очереди соблюдаются, но по сравнению с ExpressionModel хранилище упрощено:
+// таски выдаются по приоритету выражения и длине критического пути без старения и справедливого
+// планировщика по пользователям, история тасок не ведется, а выражения с наступившим дедлайном
+// не снимаются с очереди, их таски просто не выдаются. Квот, кэша результатов, вебхуков и
+// выборки лидера нет
+type MemoryRepository struct {
+	// Clock - источник текущего времени. Если не задан, используется time.Now
+	Clock func() time.Time
+
+	mu          sync.Mutex
+	users       map[int]*models.User
+	expressions map[int]*models.Expression
+	tasks       map[int]*models.Task
+	agents      map[string]*models.Agent
+	lastUserID  int
+	lastExprID  int
+	lastTaskID  int
+	queueState  models.QueueState
+
+	ready repository.ReadyNotifier
+}
+
+// NewMemoryRepository создает пустое хранилище в памяти
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:       make(map[int]*models.User),
+		expressions: make(map[int]*models.Expression),
+		tasks:       make(map[int]*models.Task),
+		agents:      make(map[string]*models.Agent),
+	}
+}
+
+func (m *MemoryRepository) now() time.Time {
+	if m.Clock != nil {
+		return m.Clock()
+	}
+	return time.Now()
+}
+
+// CreateUser сохраняет юзера. Логин должен быть уникальным
+func (m *MemoryRepository) CreateUser(user *models.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Login == user.Login {
+			return 0, fmt.Errorf("%w: login %s is already taken", models.ErrorCreatingDatabaseRecord, user.Login)
+		}
+	}
+
+	m.lastUserID++
+	stored := *user
+	stored.ID = m.lastUserID
+	stored.CreatedAt = m.now()
+	if stored.Role == "" {
+		stored.Role = models.RoleUser
+	}
+	if stored.Weight <= 0 {
+		stored.Weight = 1
+	}
+	m.users[stored.ID] = &stored
+
+	user.ID = stored.ID
+	return stored.ID, nil
+}
+
+// GetUserByLogin возвращает данные юзера по логину
+func (m *MemoryRepository) GetUserByLogin(login string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Login == login {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, models.ErrorUserNotFound
+}
+
+// GetUserByID возвращает данные юзера по его ID
+func (m *MemoryRepository) GetUserByID(userID int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, models.ErrorUserNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+// Insert сохраняет мат выражение
+func (m *MemoryRepository) Insert(expression string, userID int) (int, error) {
+	return m.InsertExpression(&models.Expression{UserID: userID, Expression: expression})
+}
+
+// InsertExpression сохраняет мат выражение вместе с его параметрами (приоритетом и т.д.).
+// Пока очередь останавливается, возвращает models.ErrorQueueDraining
+func (m *MemoryRepository) InsertExpression(expr *models.Expression) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.queueState.Draining {
+		return 0, models.ErrorQueueDraining
+	}
+
+	m.lastExprID++
+	createdAt := m.now()
+
+	stored := *expr
+	stored.ID = m.lastExprID
+	stored.Status = models.StatusWait
+	stored.CreatedAt = &createdAt
+	m.expressions[stored.ID] = &stored
+
+	expr.ID = stored.ID
+	expr.CreatedAt = &createdAt
+	return stored.ID, nil
+}
+
+// GetExpression возвращает выражение по ID
+func (m *MemoryRepository) GetExpression(exprID int) (*models.Expression, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expr, ok := m.expressions[exprID]
+	if !ok {
+		return nil, fmt.Errorf("fail to get expression ID-%d: %w", exprID, models.ErrorExpressionNotFound)
+	}
+	found := *expr
+	return &found, nil
+}
+
+// UpdateStatus устанавливает актуальный статус выражения
+func (m *MemoryRepository) UpdateStatus(id int, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expr, ok := m.expressions[id]
+	if !ok {
+		return
+	}
+	expr.Status = status
+	if status == models.StatusResolved || status == models.StatusFailed {
+		finishedAt := m.now()
+		expr.FinishedAt = &finishedAt
+	}
+}
+
+// InsertTask сохраняет таску. Приоритет таска наследует от своего выражения
+func (m *MemoryRepository) InsertTask(task *models.Task) (int, error) {
+	m.mu.Lock()
+
+	m.lastTaskID++
+	createdAt := m.now()
+
+	stored := *task
+	stored.ID = m.lastTaskID
+	stored.Priority = 0
+	if expr, ok := m.expressions[task.ExpressionID]; ok {
+		stored.Priority = expr.Priority
+	}
+	stored.CreatedAt = &createdAt
+	m.tasks[stored.ID] = &stored
+
+	m.mu.Unlock()
+
+	m.NotifyTasksReady()
+	return stored.ID, nil
+}
+
+// GetTask выдает агенту готовую к выполнению таску: с наибольшим приоритетом, затем с самым длинным
+// критическим путем, затем самую старую. Вместо операндов, которые зависят от других тасок, подставляются их результаты.
+// Пока выдача тасок приостановлена, возвращает models.ErrorQueuePaused
+func (m *MemoryRepository) GetTask(filter models.TaskFilter) (*models.Task, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.queueState.Paused {
+		return nil, 0, models.ErrorQueuePaused
+	}
+
+	now := m.now()
+
+	var chosen *models.Task
+	for _, task := range m.tasks {
+		if !m.isReady(task, filter, now) {
+			continue
+		}
+		if chosen == nil || outranks(task, chosen) {
+			chosen = task
+		}
+	}
+	if chosen == nil {
+		return nil, 0, nil
+	}
+
+	chosen.Status = models.StatusInProcess
+	chosen.AgentID = filter.AgentID
+	chosen.StartedAt = &now
+	if expr, ok := m.expressions[chosen.ExpressionID]; ok && expr.StartedAt == nil {
+		expr.StartedAt = &now
+	}
+
+	task := *chosen
+	if prev, ok := m.tasks[task.PrevTaskID1]; ok {
+		task.Arg1 = prev.Result
+	}
+	if prev, ok := m.tasks[task.PrevTaskID2]; ok {
+		task.Arg2 = prev.Result
+	}
+	if expr, ok := m.expressions[task.ExpressionID]; ok {
+		task.Deadline = expr.Deadline
+	}
+
+	return &task, task.ID, nil
+}
+
+// isReady проверяет, что таска ждет выполнения, ее зависимости посчитаны, дедлайн выражения не наступил
+// и агент умеет выполнять ее операцию
+func (m *MemoryRepository) isReady(task *models.Task, filter models.TaskFilter, now time.Time) bool {
+	if task.Status != models.StatusWait {
+		return false
+	}
+	for _, prevID := range []int{task.PrevTaskID1, task.PrevTaskID2} {
+		if prevID == 0 {
+			continue
+		}
+		if prev, ok := m.tasks[prevID]; !ok || prev.Status != models.StatusResolved {
+			return false
+		}
+	}
+	if expr, ok := m.expressions[task.ExpressionID]; ok && expr.Deadline != nil && !expr.Deadline.After(now) {
+		return false
+	}
+	return len(filter.Operations) == 0 || slices.Contains(filter.Operations, task.Operation)
+}
+
+// outranks сообщает, что таску a нужно выдать раньше таски b
+func outranks(a, b *models.Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if a.CriticalPathMS != b.CriticalPathMS {
+		return a.CriticalPathMS > b.CriticalPathMS
+	}
+	return a.ID < b.ID
+}
+
+// GetTaskStatus возвращает статус и ответ таски
+func (m *MemoryRepository) GetTaskStatus(taskID int) (string, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, ok := m.tasks[taskID]
+	if !ok {
+		return "", 0, fmt.Errorf("task ID-%d not found", taskID)
+	}
+	return task.Status, task.Result, nil
+}
+
+// UpdateTaskStatus обновляет статус таски
+func (m *MemoryRepository) UpdateTaskStatus(taskID int, status string) {
+	m.mu.Lock()
+	task, ok := m.tasks[taskID]
+	if ok {
+		task.Status = status
+	}
+	m.mu.Unlock()
+
+	if ok && (status == models.StatusWait || status == models.StatusNew) {
+		m.NotifyTasksReady()
+	}
+}
+
+// RequeueTask возвращает в очередь таску, выданную агенту, который перестал выходить на связь.
+// Таска, результат которой уже получен или которая снята с очереди, не меняется
+func (m *MemoryRepository) RequeueTask(taskID int) (bool, error) {
+	m.mu.Lock()
+	task, ok := m.tasks[taskID]
+	requeued := ok && task.Status == models.StatusInProcess
+	if requeued {
+		task.Status = models.StatusWait
+		task.AgentID = ""
+		task.StartedAt = nil
+	}
+	m.mu.Unlock()
+
+	if requeued {
+		m.NotifyTasksReady()
+	}
+	return requeued, nil
+}
+
+// UpdateTaskResult записывает результат таски и, если все таски выражения посчитаны, его окончательный ответ.
+// Принимается только результат таски, которая сейчас выдана агенту
+func (m *MemoryRepository) UpdateTaskResult(taskID int, result float64, errorMessage string) error {
+	m.mu.Lock()
+
+	task, ok := m.tasks[taskID]
+	if !ok || task.Status != models.StatusInProcess {
+		m.mu.Unlock()
+		log.Printf("result for task ID-%d ignored: task is not being calculated or does not exist", taskID)
+		return nil
+	}
+
+	finishedAt := m.now()
+	task.Status = models.StatusResolved
+	task.Result = result
+	task.ErrorMessage = errorMessage
+	task.FinishedAt = &finishedAt
+
+	err := m.finishExpression(task.ExpressionID, finishedAt)
+	m.mu.Unlock()
+
+	m.NotifyTasksReady()
+	return err
+}
+
+// finishExpression записывает ответ выражения, если все его таски посчитаны: результат последней таски
+// или первую ошибку. Вызывается под m.mu
+func (m *MemoryRepository) finishExpression(exprID int, finishedAt time.Time) error {
+	expr, ok := m.expressions[exprID]
+	if !ok {
+		return fmt.Errorf("expression with ID %d does not exist", exprID)
+	}
+
+	var exprTasks []*models.Task
+	for _, task := range m.tasks {
+		if task.ExpressionID != exprID {
+			continue
+		}
+		if task.Status != models.StatusResolved {
+			return nil
+		}
+		exprTasks = append(exprTasks, task)
+	}
+	slices.SortFunc(exprTasks, func(a, b *models.Task) int { return a.ID - b.ID })
+
+	expr.Status = models.StatusResolved
+	expr.ErrorMessage = ""
+	expr.FinishedAt = &finishedAt
+	for _, task := range exprTasks {
+		if task.ErrorMessage != "" {
+			expr.Status = models.StatusFailed
+			expr.Result = 0
+			expr.ErrorMessage = task.ErrorMessage
+			return nil
+		}
+		expr.Result = task.Result
+	}
+
+	return nil
+}
+
+// GetQueueState возвращает режим работы очереди
+func (m *MemoryRepository) GetQueueState() (models.QueueState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.queueState, nil
+}
+
+// SetQueueState меняет режим работы очереди. Когда выдача тасок возобновляется, ожидающие агенты будятся сразу
+func (m *MemoryRepository) SetQueueState(state models.QueueState) error {
+	m.mu.Lock()
+	m.queueState = state
+	m.mu.Unlock()
+
+	if !state.Paused {
+		m.NotifyTasksReady()
+	}
+	return nil
+}
+
+// RegisterAgent сохраняет агента. Повторная регистрация обновляет сведения об агенте,
+// но сохраняет время первой регистрации
+func (m *MemoryRepository) RegisterAgent(agent *models.Agent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	stored := *agent
+	stored.Operations = slices.Clone(agent.Operations)
+	stored.RegisteredAt = now
+	stored.LastSeen = now
+	if existing, ok := m.agents[agent.ID]; ok {
+		stored.RegisteredAt = existing.RegisteredAt
+	}
+	m.agents[agent.ID] = &stored
+
+	return nil
+}
+
+// TouchAgent отмечает, что агент на связи
+func (m *MemoryRepository) TouchAgent(agentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, ok := m.agents[agentID]
+	if !ok {
+		return models.ErrorAgentNotFound
+	}
+	agent.LastSeen = m.now()
+	return nil
+}
+
+// TasksReady возвращает канал, который закроется, когда в очереди появятся новые таски
+// или у ожидающих тасок разрешатся зависимости
+func (m *MemoryRepository) TasksReady() <-chan struct{} {
+	return m.ready.Subscribe()
+}
+
+// NotifyTasksReady будит всех, кто ждет готовых тасок
+func (m *MemoryRepository) NotifyTasksReady() {
+	m.ready.Notify()
+}
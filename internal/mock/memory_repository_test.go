@@ -0,0 +1,246 @@
+package mock_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/mock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository_Users(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+
+	user := &models.User{Login: "alice", PasswordHash: "hash"}
+	id, err := repo.CreateUser(user)
+	require.NoError(t, err)
+	assert.Equal(t, id, user.ID)
+
+	_, err = repo.CreateUser(&models.User{Login: "alice", PasswordHash: "other"})
+	assert.ErrorIs(t, err, models.ErrorCreatingDatabaseRecord)
+
+	byLogin, err := repo.GetUserByLogin("alice")
+	require.NoError(t, err)
+	assert.Equal(t, id, byLogin.ID)
+	assert.Equal(t, "hash", byLogin.PasswordHash)
+
+	byID, err := repo.GetUserByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, byID.Role)
+	assert.Equal(t, 1, byID.Weight)
+
+	_, err = repo.GetUserByLogin("bob")
+	assert.ErrorIs(t, err, models.ErrorUserNotFound)
+	_, err = repo.GetUserByID(id + 1)
+	assert.ErrorIs(t, err, models.ErrorUserNotFound)
+}
+
+func TestMemoryRepository_ExpressionLifecycle(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+
+	exprID, err := repo.Insert("(2+3)*4", 1)
+	require.NoError(t, err)
+
+	sumID, err := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 3, Operation: "+", Status: models.StatusWait})
+	require.NoError(t, err)
+	mulID, err := repo.InsertTask(&models.Task{ExpressionID: exprID, PrevTaskID1: sumID, Arg2: 4, Operation: "*", Status: models.StatusWait})
+	require.NoError(t, err)
+
+	task, id, err := repo.GetTask(models.TaskFilter{AgentID: "agent-1"})
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, sumID, id)
+	assert.Equal(t, "agent-1", task.AgentID)
+
+	next, _, err := repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	assert.Nil(t, next, "multiplication waits for the sum")
+
+	ready := repo.TasksReady()
+	require.NoError(t, repo.UpdateTaskResult(sumID, 5, ""))
+	select {
+	case <-ready:
+	default:
+		t.Fatal("result of a task must wake up waiting dispatchers")
+	}
+
+	task, _, err = repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, mulID, task.ID)
+	assert.Equal(t, float64(5), task.Arg1)
+
+	expr, err := repo.GetExpression(exprID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusWait, expr.Status)
+	assert.NotNil(t, expr.StartedAt)
+
+	require.NoError(t, repo.UpdateTaskResult(mulID, 20, ""))
+
+	expr, err = repo.GetExpression(exprID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusResolved, expr.Status)
+	assert.Equal(t, float64(20), expr.Result)
+	assert.NotNil(t, expr.FinishedAt)
+
+	_, err = repo.GetExpression(exprID + 1)
+	assert.ErrorIs(t, err, models.ErrorExpressionNotFound)
+}
+
+func TestMemoryRepository_TaskError(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+
+	exprID, _ := repo.Insert("1/0", 1)
+	taskID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Operation: "/", Status: models.StatusWait})
+
+	_, _, err := repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateTaskResult(taskID, 0, "division by zero"))
+
+	expr, err := repo.GetExpression(exprID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, expr.Status)
+	assert.Equal(t, "division by zero", expr.ErrorMessage)
+}
+
+func TestMemoryRepository_GetTaskOrder(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := mock.NewMemoryRepository()
+	repo.Clock = func() time.Time { return now }
+
+	low, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1+1"})
+	high, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "2*2", Priority: 5})
+	expired := now.Add(-time.Second)
+	late, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "3-3", Priority: 10, Deadline: &expired})
+
+	lowID, _ := repo.InsertTask(&models.Task{ExpressionID: low, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait})
+	shortID, _ := repo.InsertTask(&models.Task{ExpressionID: high, Arg1: 2, Arg2: 2, Operation: "*", Status: models.StatusWait, CriticalPathMS: 10})
+	longID, _ := repo.InsertTask(&models.Task{ExpressionID: high, Arg1: 2, Arg2: 2, Operation: "-", Status: models.StatusWait, CriticalPathMS: 20})
+	repo.InsertTask(&models.Task{ExpressionID: late, Arg1: 3, Arg2: 3, Operation: "-", Status: models.StatusWait})
+
+	task, _, _ := repo.GetTask(models.TaskFilter{Operations: []string{"+", "*"}})
+	require.NotNil(t, task)
+	assert.Equal(t, shortID, task.ID, "operations the agent can't do are skipped")
+
+	task, _, _ = repo.GetTask(models.TaskFilter{})
+	require.NotNil(t, task)
+	assert.Equal(t, longID, task.ID)
+
+	task, _, _ = repo.GetTask(models.TaskFilter{})
+	require.NotNil(t, task)
+	assert.Equal(t, lowID, task.ID, "tasks of expired expressions are not dispatched")
+
+	task, _, _ = repo.GetTask(models.TaskFilter{})
+	assert.Nil(t, task)
+}
+
+func TestMemoryRepository_RequeueTask(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+
+	exprID, _ := repo.Insert("2+2", 1)
+	taskID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	requeued, err := repo.RequeueTask(taskID)
+	require.NoError(t, err)
+	assert.False(t, requeued, "waiting task is not requeued")
+
+	repo.GetTask(models.TaskFilter{AgentID: "agent-1"})
+	requeued, err = repo.RequeueTask(taskID)
+	require.NoError(t, err)
+	assert.True(t, requeued)
+
+	status, _, err := repo.GetTaskStatus(taskID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusWait, status)
+
+	repo.UpdateTaskStatus(taskID, models.StatusFailed)
+	require.NoError(t, repo.UpdateTaskResult(taskID, 4, ""))
+	status, _, err = repo.GetTaskStatus(taskID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, status, "result of a withdrawn task is ignored")
+
+	_, _, err = repo.GetTaskStatus(taskID + 1)
+	assert.Error(t, err)
+}
+
+func TestMemoryRepository_Agents(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := mock.NewMemoryRepository()
+	repo.Clock = func() time.Time { return now }
+
+	assert.ErrorIs(t, repo.TouchAgent("agent-1"), models.ErrorAgentNotFound)
+
+	require.NoError(t, repo.RegisterAgent(&models.Agent{ID: "agent-1", Workers: 2}))
+	now = now.Add(time.Minute)
+	require.NoError(t, repo.RegisterAgent(&models.Agent{ID: "agent-1", Workers: 4}))
+	assert.NoError(t, repo.TouchAgent("agent-1"))
+}
+
+func TestMemoryRepository_ConcurrentDispatch(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+
+	exprID, _ := repo.Insert("1+1", 1)
+	for i := 0; i < 50; i++ {
+		repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait})
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed = make(map[int]int)
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, _, err := repo.GetTask(models.TaskFilter{})
+				if err != nil || task == nil {
+					return
+				}
+				mu.Lock()
+				claimed[task.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, 50)
+	for id, count := range claimed {
+		assert.Equal(t, 1, count, "task ID-%d dispatched more than once", id)
+	}
+}
+
+func TestMemoryRepository_QueueState(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+
+	exprID, _ := repo.Insert("2+2", 1)
+	repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	require.NoError(t, repo.SetQueueState(models.QueueState{Paused: true, Draining: true}))
+
+	_, _, err := repo.GetTask(models.TaskFilter{})
+	assert.ErrorIs(t, err, models.ErrorQueuePaused)
+	_, err = repo.Insert("3+3", 1)
+	assert.ErrorIs(t, err, models.ErrorQueueDraining)
+
+	ready := repo.TasksReady()
+	require.NoError(t, repo.SetQueueState(models.QueueState{Draining: true}))
+	select {
+	case <-ready:
+	default:
+		t.Fatal("resuming the queue must wake up waiting dispatchers")
+	}
+
+	task, _, err := repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	assert.NotNil(t, task, "draining queue still dispatches started expressions")
+
+	state, err := repo.GetQueueState()
+	require.NoError(t, err)
+	assert.Equal(t, models.QueueState{Draining: true}, state)
+}
@@ -10,7 +10,7 @@ type ExpressionModel struct {
 }
 
 // GetTask — заглушка, возвращающая nil и ошибку
-func (m *ExpressionModel) GetTask() (*models.Task, int, error) {
+func (m *ExpressionModel) GetTask(filter models.TaskFilter) (*models.Task, int, error) {
 	return nil, 0, nil
 }
 
@@ -32,3 +32,8 @@ func (m *ExpressionModel) Insert(expr string, userID int) (int, error) {
 // UpdateStatus — заглушка
 func (m *ExpressionModel) UpdateStatus(id int, status string) {
 }
+
+// TasksReady — заглушка, новые таски никогда не появляются
+func (m *ExpressionModel) TasksReady() <-chan struct{} {
+	return nil
+}
@@ -6,6 +6,12 @@ var (
 	// ErrorCreatingDatabaseRecord - ошибка записи в БД
 	ErrorCreatingDatabaseRecord = errors.New("an error occurred while writing to the database")
 
+	// ErrorDeadlineExceeded - выражение не успело посчитаться до своего дедлайна
+	ErrorDeadlineExceeded = errors.New("deadline exceeded")
+
+	// ErrorInvalidDeadline - дедлайн выражения уже прошел в момент отправки
+	ErrorInvalidDeadline = errors.New("deadline must be in the future")
+
 	// ErrorDivisionByZero - ошибка деления на ноль
 	ErrorDivisionByZero = errors.New("division by zero")
 
@@ -21,15 +27,54 @@ var (
 	// ErrorInvalidInput - невалидное выражение
 	ErrorInvalidInput = errors.New("expression is not valid")
 
+	// ErrorInvalidCallbackURL - адрес уведомлений должен быть абсолютным http или https URL
+	ErrorInvalidCallbackURL = errors.New("callback URL must be an absolute http or https URL")
+
+	// ErrorCallbackHostNotAllowed - уведомления отправляются только на публичные адреса, а не во внутреннюю сеть оркестратора
+	ErrorCallbackHostNotAllowed = errors.New("callback URL must point to a public address")
+
+	// ErrorWebhookSecretNotSet - ключ подписи уведомлений не задан или остался заглушкой, такие подписи ничего не доказывают
+	ErrorWebhookSecretNotSet = errors.New("webhook secret is not set")
+
 	// ErrorInvalidOperand - ошибка при введении операнда
 	ErrorInvalidOperand = errors.New("an invalid operand")
 
+	// ErrorInvalidWeight - вес пользователя в планировщике должен быть положительным
+	ErrorInvalidWeight = errors.New("weight must be a positive integer")
+
+	// ErrorPriorityOutOfRange - приоритет выражения выходит за пределы, разрешенные роли пользователя
+	ErrorPriorityOutOfRange = errors.New("priority is out of the range allowed for the user's role")
+
 	// ErrorInvalidRequestBody - ошибка тела запроса
 	ErrorInvalidRequestBody = errors.New("invalid request body")
 
 	// ErrorUserNotFound - пользователь не найдет
 	ErrorUserNotFound = errors.New("user not found")
 
+	// ErrorExpressionNotFound - выражения с таким ID нет
+	ErrorExpressionNotFound = errors.New("expression not found")
+
+	// ErrorInvalidSortKey - по этому полю нельзя сортировать выражения
+	ErrorInvalidSortKey = errors.New("invalid sort key")
+
+	// ErrorAgentNotFound - агент не зарегистрирован в оркестраторе
+	ErrorAgentNotFound = errors.New("agent not found")
+
+	// ErrorQuotaExceeded - пользователь исчерпал один из своих лимитов
+	ErrorQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrorInvalidQuota - лимит не может быть отрицательным
+	ErrorInvalidQuota = errors.New("quota limits must not be negative")
+
+	// ErrorQueuePaused - администратор приостановил выдачу тасок агентам
+	ErrorQueuePaused = errors.New("paused")
+
+	// ErrorQueueDraining - оркестратор завершает начатые выражения и не принимает новые
+	ErrorQueueDraining = errors.New("orchestrator is draining and does not accept new expressions")
+
+	// ErrorQueueOverloaded - в очереди слишком много тасок, новые выражения временно не принимаются
+	ErrorQueueOverloaded = errors.New("too many queued tasks, retry later")
+
 	// ErrorReceivingID - ошибка, которая возникает, не удается получить айди последней записи в БД
 	ErrorReceivingID = errors.New("failed to get ID records in the database")
 
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 var (
 	// StatusInProcess указываеь таски, над которыми сейчас работает воркер
@@ -19,6 +22,44 @@ var (
 	StatusWait = "awaiting processing"
 )
 
+var (
+	// CacheHit указывает в ответе, что результат выражения взят из кэша
+	CacheHit = "hit"
+
+	// CacheMiss указывает в ответе, что результата в кэше не было и выражение отправлено на вычисление
+	CacheMiss = "miss"
+)
+
+var (
+	// DeliveryPending - уведомление еще не доставлено, но попытки продолжаются
+	DeliveryPending = "pending"
+
+	// DeliveryDelivered - получатель ответил на уведомление кодом 2xx
+	DeliveryDelivered = "delivered"
+
+	// DeliveryFailed - все попытки доставить уведомление исчерпаны
+	DeliveryFailed = "failed"
+)
+
+var (
+	// AgentStateBusy - агент на связи и выполняет таски
+	AgentStateBusy = "busy"
+
+	// AgentStateIdle - агент на связи, но тасок у него нет
+	AgentStateIdle = "idle"
+
+	// AgentStateOffline - агент давно не присылал heartbeat
+	AgentStateOffline = "offline"
+)
+
+var (
+	// RoleAdmin - роль администратора, которому доступны эндпоинты /api/v1/admin
+	RoleAdmin = "admin"
+
+	// RoleUser - роль обычного пользователя, выдается при регистрации
+	RoleUser = "user"
+)
+
 // ContextKey - тип для ключа контекста
 type ContextKey string
 
@@ -35,32 +76,140 @@ type ErrorResponse struct {
 
 // Expression - структура математического выражения
 type Expression struct {
-	ID           int     `json:"id"`
-	UserID       int     `json:"user_id"`
-	Expression   string  `json:"expression"`
-	Status       string  `json:"status"`
-	Result       float64 `json:"result"`
-	ErrorMessage string  `json:"error_message"`
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	Expression   string     `json:"expression"`
+	Status       string     `json:"status"`
+	Result       float64    `json:"result"`
+	ErrorMessage string     `json:"error_message"`
+	Priority     int        `json:"priority"`
+	Deadline     *time.Time `json:"deadline,omitempty"`
+
+	// CallbackURL - куда отправить уведомление о завершении выражения. Если не задан, используется вебхук пользователя
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// CreatedAt - когда выражение принято, StartedAt - когда агент взял в работу первую таску,
+	// FinishedAt - когда выражение посчитано или провалено
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// EstimatedCompletion - ожидаемое время завершения еще не посчитанного выражения.
+	// Не хранится в базе, а пересчитывается по оставшимся таскам и очереди перед ними при каждом запросе
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+}
+
+// ExpressionFilter - условия выборки и сортировки списка выражений. Пустые поля не ограничивают выборку
+type ExpressionFilter struct {
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	StartedAfter   *time.Time
+	StartedBefore  *time.Time
+	FinishedAfter  *time.Time
+	FinishedBefore *time.Time
+
+	// SortBy - created_at, started_at или finished_at. По умолчанию выражения идут в порядке ID
+	SortBy     string
+	Descending bool
 }
 
 // ExpressionRepository — интерфейс для работы с задачами и выражениями
 type ExpressionRepository interface {
-	GetTask() (*Task, int, error)
+	GetTask(filter TaskFilter) (*Task, int, error)
 	UpdateTaskResult(id int, result float64, err string) error
 	GetExpression(id int) (*Expression, error)
 	Insert(expr string, userID int) (int, error)
 	UpdateStatus(id int, status string)
 	UpdateTaskStatus(id int, status string)
+	GetTaskStatus(id int) (string, float64, error)
+	RequeueTask(id int) (bool, error)
+	TasksReady() <-chan struct{}
+}
+
+// Quota описывает лимиты пользователя. Нулевое значение означает отсутствие ограничения
+type Quota struct {
+	MaxActiveExpressions  int `json:"max_active_expressions"`
+	MaxTasksPerExpression int `json:"max_tasks_per_expression"`
+	MaxQueuedTasks        int `json:"max_queued_tasks"`
+}
+
+// QuotaOverride - персональные лимиты пользователя, заданные администратором.
+// Поле со значением null возвращает соответствующий лимит к значению по умолчанию
+type QuotaOverride struct {
+	MaxActiveExpressions  *int `json:"max_active_expressions"`
+	MaxTasksPerExpression *int `json:"max_tasks_per_expression"`
+	MaxQueuedTasks        *int `json:"max_queued_tasks"`
+}
+
+// QuotaUsage - текущее потребление ресурсов пользователем
+type QuotaUsage struct {
+	ActiveExpressions int `json:"active_expressions"`
+	QueuedTasks       int `json:"queued_tasks"`
+}
+
+// QuotaError - структура ответа, возвращаемого при превышении лимита
+type QuotaError struct {
+	Error        string `json:"error"`
+	ErrorMessage string `json:"error_message"`
+	Quota        string `json:"quota"`
+	Limit        int    `json:"limit"`
+	Usage        int    `json:"usage"`
+	Requested    int    `json:"requested"`
+}
+
+// UserQuota - лимиты пользователя вместе с текущим потреблением
+type UserQuota struct {
+	UserID int        `json:"user_id"`
+	Limits Quota      `json:"limits"`
+	Usage  QuotaUsage `json:"usage"`
 }
 
 // RegisteredExpression - структура ответа, возвращаемого при регистрации выражения в оркестраторе
 type RegisteredExpression struct {
-	ID int `json:"id"`
+	ID         int    `json:"id"`
+	Tasks      int    `json:"tasks"`
+	TasksSaved int    `json:"tasks_saved"`
+	Cache      string `json:"cache,omitempty"`
+
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+}
+
+// QueueState - режим работы очереди, которым управляют администраторы.
+// Paused - таски не выдаются агентам, Draining - новые выражения не принимаются
+type QueueState struct {
+	Paused   bool `json:"paused"`
+	Draining bool `json:"draining"`
+}
+
+// QueueStatus - режим очереди вместе с объемом незавершенной работы
+type QueueStatus struct {
+	QueueState
+	ActiveExpressions int `json:"active_expressions"`
+	QueuedTasks       int `json:"queued_tasks"`
+	RunningTasks      int `json:"running_tasks"`
+}
+
+// CacheFlushResult - ответ на сброс кэша результатов
+type CacheFlushResult struct {
+	Flushed int `json:"flushed"`
+}
+
+// ParseStats - статистика разбора выражения на таски.
+// TasksSaved - сколько тасок не было создано благодаря переиспользованию одинаковых подвыражений
+type ParseStats struct {
+	Tasks      int
+	TasksSaved int
 }
 
 // Request - структура запроса
 type Request struct {
-	Expression string `json:"expression"`
+	Expression string     `json:"expression"`
+	Priority   int        `json:"priority"`
+	Deadline   *time.Time `json:"deadline"`
+	UseCache   bool       `json:"use_cache"`
+
+	// CallbackURL - адрес, на который придет POST, когда выражение посчитается или провалится
+	CallbackURL string `json:"callback_url"`
 }
 
 // Response - струтура ответа после успешного завершения программы
@@ -70,15 +219,67 @@ type Response struct {
 
 // Task описывает задачу для выполнения
 type Task struct {
-	ID           int     `json:"ID"`
-	ExpressionID int     `json:"ExpressionID"`
-	Arg1         float64 `json:"Arg1"`
-	Arg2         float64 `json:"Arg2"`
-	PrevTaskID1  int     `json:"PrevTaskID1"`
-	PrevTaskID2  int     `json:"PrevTaskID2"`
-	Operation    string  `json:"Operation"`
-	Status       string  `json:"Status"`
-	Result       float64 `json:"Result"`
+	ID           int        `json:"ID"`
+	ExpressionID int        `json:"ExpressionID"`
+	Arg1         float64    `json:"Arg1"`
+	Arg2         float64    `json:"Arg2"`
+	PrevTaskID1  int        `json:"PrevTaskID1"`
+	PrevTaskID2  int        `json:"PrevTaskID2"`
+	Operation    string     `json:"Operation"`
+	Status       string     `json:"Status"`
+	Result       float64    `json:"Result"`
+	ErrorMessage string     `json:"ErrorMessage,omitempty"`
+	AgentID      string     `json:"AgentID,omitempty"`
+	Priority     int        `json:"Priority"`
+	Deadline     *time.Time `json:"Deadline,omitempty"`
+
+	// CriticalPathMS - сколько миллисекунд занимает самая длинная цепочка тасок от этой таски до конца выражения
+	CriticalPathMS int64 `json:"CriticalPathMS"`
+
+	CreatedAt  *time.Time `json:"CreatedAt,omitempty"`
+	StartedAt  *time.Time `json:"StartedAt,omitempty"`
+	FinishedAt *time.Time `json:"FinishedAt,omitempty"`
+}
+
+// TaskFilter описывает агента, запрашивающего таску
+type TaskFilter struct {
+	AgentID string
+
+	// Operations - операции, которые умеет выполнять агент. Пустой список означает любые операции
+	Operations []string
+}
+
+// TaskEvent - смена статуса таски. AgentID заполнен, если в этот момент таска была у агента
+type TaskEvent struct {
+	ID           int       `json:"id"`
+	TaskID       int       `json:"task_id"`
+	ExpressionID int       `json:"expression_id"`
+	Status       string    `json:"status"`
+	AgentID      string    `json:"agent_id,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Agent описывает агента, зарегистрированного в оркестраторе
+type Agent struct {
+	ID           string    `json:"id"`
+	Hostname     string    `json:"hostname"`
+	Version      string    `json:"version"`
+	Workers      int       `json:"workers"`
+	Operations   []string  `json:"operations"`
+	RegisteredAt time.Time `json:"registered_at"`
+	LastSeen     time.Time `json:"last_seen"`
+	State        string    `json:"state"`
+	CurrentTasks []int     `json:"current_tasks"`
+
+	// Throughput - сколько тасок агент посчитал за последнюю минуту
+	Throughput int `json:"throughput_per_minute"`
+}
+
+// AgentRepository — интерфейс для учета агентов
+type AgentRepository interface {
+	RegisterAgent(agent *Agent) error
+	TouchAgent(agentID string) error
 }
 
 // TaskResponse - структура, содержащая одну таску
@@ -98,4 +299,44 @@ type User struct {
 	Login        string    `json:"login"`
 	PasswordHash string    `json:"password_hash"`
 	CreatedAt    time.Time `json:"created_at"`
+	Role         string    `json:"role"`
+	Weight       int       `json:"weight"`
+}
+
+// Webhook - адрес, на который по умолчанию отправляются уведомления о завершении выражений пользователя.
+// Пустой URL отключает уведомления
+type Webhook struct {
+	URL string `json:"url"`
+}
+
+// WebhookPayload - тело уведомления о завершении выражения
+type WebhookPayload struct {
+	ExpressionID int        `json:"expression_id"`
+	Expression   string     `json:"expression"`
+	Status       string     `json:"status"`
+	Result       float64    `json:"result"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// WebhookDelivery - запись исходящей очереди уведомлений и история попыток ее доставки
+type WebhookDelivery struct {
+	ID           int             `json:"id"`
+	ExpressionID int             `json:"expression_id"`
+	URL          string          `json:"url"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       string          `json:"status"`
+	Attempts     int             `json:"attempts"`
+	ResponseCode int             `json:"response_code,omitempty"`
+	LastError    string          `json:"last_error,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+
+	// NextAttemptAt - когда будет следующая попытка, пока уведомление не доставлено
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// UserWeight - структура запроса, которым администратор задает вес пользователя в планировщике
+type UserWeight struct {
+	Weight int `json:"weight"`
 }
@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// PromoteAdmin при запуске оркестратора назначает роль администратора уже зарегистрированному
+// пользователю с логином login. Публичная регистрация роль не выдает, поэтому администратор
+// сначала регистрируется как обычный пользователь, а затем оркестратор перезапускается с admin.LOGIN.
+// Пустой login ничего не меняет, отсутствие такого пользователя не считается ошибкой
+func PromoteAdmin(exprRepo *repository.ExpressionModel, login string) error {
+	if login == "" {
+		return nil
+	}
+
+	user, err := exprRepo.GetUserByLogin(login)
+	if errors.Is(err, models.ErrorUserNotFound) {
+		log.Printf("admin user %q is not registered yet, admin role is not granted", login)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find admin user %q: %v", login, err)
+	}
+
+	if err := exprRepo.SetUserRole(user.ID, models.RoleAdmin); err != nil {
+		return fmt.Errorf("failed to grant admin role to %q: %v", login, err)
+	}
+
+	log.Printf("admin role granted to %q", login)
+	return nil
+}
@@ -0,0 +1,35 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoteAdmin(t *testing.T) {
+	repo := newWebhookRepo(t)
+
+	require.NoError(t, PromoteAdmin(repo, "root"))
+
+	adminID, err := repo.CreateUser(&models.User{Login: "root", PasswordHash: "hash"})
+	require.NoError(t, err)
+	userID, err := repo.CreateUser(&models.User{Login: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+
+	require.NoError(t, PromoteAdmin(repo, ""))
+	admin, err := repo.GetUserByID(adminID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, admin.Role)
+
+	require.NoError(t, PromoteAdmin(repo, "root"))
+
+	admin, err = repo.GetUserByID(adminID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+
+	user, err := repo.GetUserByID(userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, user.Role)
+}
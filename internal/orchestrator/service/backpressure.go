@@ -0,0 +1,49 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// throughputWindow - за какой период считается пропускная способность кластера
+const throughputWindow = time.Minute
+
+// Backpressure - порог общей очереди тасок, после которого новые выражения отклоняются.
+// Нулевой MaxQueuedTasks отключает проверку
+type Backpressure struct {
+	MaxQueuedTasks int
+	MaxRetryAfter  time.Duration
+}
+
+// CheckBackpressure проверяет, не переполнена ли общая очередь тасок. Если переполнена, возвращает,
+// через сколько стоит повторить запрос
+func CheckBackpressure(exprRepo *repository.ExpressionModel, cfg Backpressure, now time.Time) (time.Duration, bool, error) {
+	if cfg.MaxQueuedTasks <= 0 {
+		return 0, false, nil
+	}
+
+	queued, finished, err := exprRepo.GetQueueLoad(now.Add(-throughputWindow))
+	if err != nil {
+		return 0, false, err
+	}
+
+	if queued <= cfg.MaxQueuedTasks {
+		return 0, false, nil
+	}
+
+	return retryAfter(queued-cfg.MaxQueuedTasks, finished, throughputWindow, cfg.MaxRetryAfter), true, nil
+}
+
+// retryAfter оценивает, за сколько кластер, посчитавший finished тасок за window, разберет excess лишних тасок.
+// Результат округляется вверх до секунды и ограничивается сверху maxWait; если кластер ничего не считает, возвращается maxWait
+func retryAfter(excess, finished int, window, maxWait time.Duration) time.Duration {
+	if finished <= 0 {
+		return maxWait
+	}
+
+	wait := time.Duration(int64(window) * int64(excess) / int64(finished))
+	wait = (wait + time.Second - 1).Truncate(time.Second)
+
+	return min(max(wait, time.Second), maxWait)
+}
@@ -0,0 +1,30 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		excess   int
+		finished int
+		expected time.Duration
+	}{
+		{name: "cluster is idle", excess: 5, finished: 0, expected: time.Minute},
+		{name: "proportional to throughput", excess: 10, finished: 60, expected: 10 * time.Second},
+		{name: "rounded up to a second", excess: 1, finished: 120, expected: time.Second},
+		{name: "partial seconds are rounded up", excess: 3, finished: 120, expected: 2 * time.Second},
+		{name: "capped", excess: 1000, finished: 10, expected: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryAfter(tt.excess, tt.finished, time.Minute, time.Minute)
+			if got != tt.expected {
+				t.Errorf("retryAfter(%d, %d) = %v, want %v", tt.excess, tt.finished, got, tt.expected)
+			}
+		})
+	}
+}
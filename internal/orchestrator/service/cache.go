@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// CanonicalForm приводит выражение к канонической записи: без пробелов и лишних скобок, с числами без лишних нулей.
+// Например, " ( 2 + 3 ) * 4" и "(2+3)*004" имеют одну и ту же запись "((2+3)*4)"
+func CanonicalForm(stringExpression string) (string, error) {
+	expression, err := tokenize(stringExpression)
+	if err != nil {
+		return "", err
+	}
+
+	if len(expression) == 0 {
+		return "", models.ErrorEmptyExpression
+	}
+
+	reversePolishNotation, err := toReversePolishNotation(expression)
+	if err != nil {
+		return "", err
+	}
+
+	var stack []string
+	for _, token := range reversePolishNotation {
+		if token.IsNumber {
+			value, err := strconv.ParseFloat(token.Value, 64)
+			if err != nil {
+				return "", fmt.Errorf("failed to parse number: %v", err)
+			}
+			stack = append(stack, numberKey(value))
+			continue
+		}
+
+		if len(stack) < 2 {
+			return "", fmt.Errorf("not enough operands for operation %s", token.Value)
+		}
+
+		key := subexpressionKey(stack[len(stack)-2], token.Value, stack[len(stack)-1])
+		stack = append(stack[:len(stack)-2], key)
+	}
+
+	if len(stack) != 1 {
+		return "", fmt.Errorf("invalid expression")
+	}
+
+	return stack[0], nil
+}
+
+// CacheKey возвращает ключ кэша результатов: каноническую запись выражения вместе с режимом точности вычислений,
+// чтобы результаты, посчитанные в разных режимах, не смешивались
+func CacheKey(stringExpression, precisionMode string) (string, error) {
+	canonical, err := CanonicalForm(stringExpression)
+	if err != nil {
+		return "", err
+	}
+	return precisionMode + ":" + canonical, nil
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import "testing"
+
+func TestCanonicalForm(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		wantErr    bool
+	}{
+		{expression: "2+2", expected: "(2+2)"},
+		{expression: " ( 2 + 3 ) * 4", expected: "((2+3)*4)"},
+		{expression: "(2+3)*004", expected: "((2+3)*4)"},
+		{expression: "((7))", expected: "7"},
+		{expression: "2+3*4", expected: "(2+(3*4))"},
+		{expression: "", wantErr: true},
+		{expression: "2@2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := CanonicalForm(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Canonical form mismatch: got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCacheKey_PrecisionMode(t *testing.T) {
+	a, _ := CacheKey("2+2", "float64")
+	b, _ := CacheKey("2 + 2", "float64")
+	c, _ := CacheKey("2+2", "decimal")
+
+	if a != b {
+		t.Errorf("Equivalent expressions must share a key: %q != %q", a, b)
+	}
+	if a == c {
+		t.Errorf("Different precision modes must not share a key: %q", a)
+	}
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// planTasks разбирает выражение в RPN на таски, не записывая их в базу.
+// Таски получают локальные ID 1..n в порядке создания, PrevTaskID ссылаются на эти ID.
+// Операнды всегда создаются раньше зависящих от них тасок
+func planTasks(reversePolishNotation []models.Token) ([]*models.Task, models.ParseStats, error) {
+	var tasks []*models.Task
+	stats, err := parseRPN(reversePolishNotation, 0, func(task *models.Task) (int, error) {
+		tasks = append(tasks, task)
+		task.ID = len(tasks)
+		return task.ID, nil
+	})
+
+	return tasks, stats, err
+}
+
+// assignCriticalPaths записывает в каждую таску длину самой длинной цепочки тасок от нее до конца выражения,
+// включая ее саму. Чем длиннее оставшийся путь, тем сильнее задержка таски откладывает результат выражения
+func assignCriticalPaths(tasks []*models.Task, durations map[string]time.Duration) {
+	tail := make([]time.Duration, len(tasks)+1)
+
+	for i := len(tasks) - 1; i >= 0; i-- {
+		task := tasks[i]
+		path := durations[task.Operation] + tail[task.ID]
+		task.CriticalPathMS = path.Milliseconds()
+
+		for _, prev := range []int{task.PrevTaskID1, task.PrevTaskID2} {
+			if prev != 0 {
+				tail[prev] = max(tail[prev], path)
+			}
+		}
+	}
+}
+
+// insertPlannedTasks записывает таски из planTasks через insert, заменяя локальные ID на настоящие
+func insertPlannedTasks(tasks []*models.Task, insert func(task *models.Task) (int, error)) error {
+	ids := make([]int, len(tasks)+1)
+
+	for _, task := range tasks {
+		localID := task.ID
+		task.ID = 0
+		task.PrevTaskID1 = ids[task.PrevTaskID1]
+		task.PrevTaskID2 = ids[task.PrevTaskID2]
+
+		taskID, err := insert(task)
+		if err != nil {
+			return fmt.Errorf("failed to insert task: %v", err)
+		}
+		ids[localID] = taskID
+	}
+
+	return nil
+}
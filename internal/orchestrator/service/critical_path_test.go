@@ -0,0 +1,37 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignCriticalPaths(t *testing.T) {
+	expression, err := tokenize("(1+2)*3-4/5")
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %v", err)
+	}
+	rpn, err := toReversePolishNotation(expression)
+	if err != nil {
+		t.Fatalf("Failed to convert to RPN: %v", err)
+	}
+
+	tasks, _, err := planTasks(rpn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assignCriticalPaths(tasks, map[string]time.Duration{
+		"+": 100 * time.Millisecond,
+		"-": 100 * time.Millisecond,
+		"*": 300 * time.Millisecond,
+		"/": 200 * time.Millisecond,
+	})
+
+	// + -> * -> - длиннее, чем / -> -
+	expected := map[string]int64{"+": 500, "*": 400, "/": 300, "-": 100}
+	for _, task := range tasks {
+		if task.CriticalPathMS != expected[task.Operation] {
+			t.Errorf("Critical path of %q: got %d ms, want %d ms", task.Operation, task.CriticalPathMS, expected[task.Operation])
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// RunDeadlineReaper раз в interval проваливает выражения, не успевшие посчитаться до дедлайна.
+// Работает, пока не отменен ctx
+func RunDeadlineReaper(ctx context.Context, exprRepo *repository.ExpressionModel, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := exprRepo.ExpireExpressions(now); err != nil {
+				log.Printf("deadline reaper: %v", err)
+			}
+		}
+	}
+}
+
+// ResolveDeadline возвращает дедлайн выражения: запрошенный пользователем или now+defaultTimeout.
+// Нулевой defaultTimeout означает, что без явного дедлайна выражение может считаться сколько угодно
+func ResolveDeadline(requested *time.Time, now time.Time, defaultTimeout time.Duration) *time.Time {
+	if requested != nil {
+		return requested
+	}
+	if defaultTimeout <= 0 {
+		return nil
+	}
+	deadline := now.Add(defaultTimeout)
+	return &deadline
+}
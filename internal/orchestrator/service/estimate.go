@@ -0,0 +1,81 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/spf13/viper"
+)
+
+// OperationDurations возвращает из конфигурации время, за которое агент выполняет каждую операцию
+func OperationDurations() map[string]time.Duration {
+	return map[string]time.Duration{
+		"+": time.Duration(viper.GetInt("duration.TIME_ADDITION_MS")) * time.Millisecond,
+		"-": time.Duration(viper.GetInt("duration.TIME_SUBTRACTION_MS")) * time.Millisecond,
+		"*": time.Duration(viper.GetInt("duration.TIME_MULTIPLICATIONS_MS")) * time.Millisecond,
+		"/": time.Duration(viper.GetInt("duration.TIME_DIVISIONS_MS")) * time.Millisecond,
+	}
+}
+
+// EstimateCompletion оценивает, когда будут посчитаны оставшиеся таски выражения.
+// Выражение не может посчитаться быстрее самой длинной цепочки зависимых тасок (критического пути)
+// и быстрее, чем workers воркеров разберут всю оставшуюся работу вместе с тасками других выражений,
+// которые будут выданы раньше (ahead - их количество по операциям), поэтому оценка - большее из двух.
+// Для уже взятой в работу таски выражения учитывается только оставшаяся часть ее времени.
+// Возвращает nil, если живых воркеров нет и оценить время нельзя
+func EstimateCompletion(tasks []models.Task, ahead map[string]int, durations map[string]time.Duration, workers int, now time.Time) *time.Time {
+	if workers <= 0 {
+		return nil
+	}
+
+	byID := make(map[int]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	var (
+		totalWork    time.Duration
+		criticalPath time.Duration
+		finish       = make(map[int]time.Duration, len(tasks))
+	)
+
+	var pathTo func(id int) time.Duration
+	pathTo = func(id int) time.Duration {
+		if d, ok := finish[id]; ok {
+			return d
+		}
+		task, ok := byID[id]
+		if !ok {
+			return 0
+		}
+
+		d := remainingDuration(task, durations, now) + max(pathTo(task.PrevTaskID1), pathTo(task.PrevTaskID2))
+		finish[id] = d
+		return d
+	}
+
+	for _, task := range tasks {
+		totalWork += remainingDuration(task, durations, now)
+		criticalPath = max(criticalPath, pathTo(task.ID))
+	}
+	for operation, count := range ahead {
+		totalWork += time.Duration(count) * durations[operation]
+	}
+
+	estimate := now.Add(max(criticalPath, totalWork/time.Duration(workers)))
+	return &estimate
+}
+
+// remainingDuration - сколько еще времени займет таска
+func remainingDuration(task models.Task, durations map[string]time.Duration, now time.Time) time.Duration {
+	switch task.Status {
+	case models.StatusResolved, models.StatusFailed:
+		return 0
+	case models.StatusInProcess:
+		if task.StartedAt != nil {
+			return max(durations[task.Operation]-now.Sub(*task.StartedAt), 0)
+		}
+	}
+
+	return durations[task.Operation]
+}
@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+func TestEstimateCompletion(t *testing.T) {
+	now := time.Now()
+	startedAt := now.Add(-40 * time.Millisecond)
+	durations := map[string]time.Duration{
+		"+": 100 * time.Millisecond,
+		"*": 300 * time.Millisecond,
+	}
+
+	// (1*2) + (3*4) + 5: два умножения можно считать параллельно, сложения идут цепочкой
+	tasks := []models.Task{
+		{ID: 1, Operation: "*", Status: models.StatusWait},
+		{ID: 2, Operation: "*", Status: models.StatusWait},
+		{ID: 3, Operation: "+", Status: models.StatusWait, PrevTaskID1: 1, PrevTaskID2: 2},
+		{ID: 4, Operation: "+", Status: models.StatusWait, PrevTaskID1: 3},
+	}
+
+	tests := []struct {
+		name     string
+		tasks    []models.Task
+		ahead    map[string]int
+		workers  int
+		expected time.Duration
+	}{
+		{name: "critical path bound", tasks: tasks, workers: 4, expected: 500 * time.Millisecond},
+		{name: "capacity bound", tasks: tasks, workers: 1, expected: 800 * time.Millisecond},
+		{
+			name: "partially done",
+			tasks: []models.Task{
+				{ID: 1, Operation: "*", Status: models.StatusResolved},
+				{ID: 2, Operation: "*", Status: models.StatusInProcess, StartedAt: &startedAt},
+				tasks[2],
+				tasks[3],
+			},
+			workers:  4,
+			expected: 460 * time.Millisecond,
+		},
+		{
+			name:     "queued work ahead",
+			tasks:    tasks,
+			ahead:    map[string]int{"*": 4, "+": 2},
+			workers:  4,
+			expected: 550 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateCompletion(tt.tasks, tt.ahead, durations, tt.workers, now)
+			if got == nil {
+				t.Fatal("Expected an estimate")
+			}
+			if got.Sub(now) != tt.expected {
+				t.Errorf("Estimate mismatch: got %v, want %v", got.Sub(now), tt.expected)
+			}
+		})
+	}
+
+	if got := EstimateCompletion(tasks, nil, durations, 0, now); got != nil {
+		t.Errorf("Expected no estimate without live workers, got %v", got)
+	}
+}
@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// statusColors задает цвет вершины графа тасок в зависимости от статуса таски
+var statusColors = map[string]string{
+	models.StatusWait:      "#d9d9d9",
+	models.StatusInProcess: "#ffe699",
+	models.StatusResolved:  "#a9d08e",
+	models.StatusFailed:    "#f4b183",
+}
+
+const defaultStatusColor = "#ffffff"
+
+// dotEscaper экранирует подпись для строки DOT в кавычках: обратный слэш, кавычки и переводы строк.
+// Замены делаются за один проход, поэтому добавленные слэши повторно не экранируются
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// mermaidEscaper экранирует подпись вершины Mermaid в кавычках; перевод строки становится переносом в подписи
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\r\n", "<br/>", "\n", "<br/>", "\r", "<br/>")
+
+// TaskGraphDOT описывает граф тасок выражения на языке Graphviz DOT.
+// Ребро идет от таски к той, которая использует ее результат как операнд
+func TaskGraphDOT(tasks []models.Task) string {
+	var b strings.Builder
+
+	b.WriteString("digraph tasks {\n")
+	b.WriteString("\tnode [shape=box, style=filled];\n")
+	for _, task := range tasks {
+		label := dotEscaper.Replace(taskLabel(task, tasks))
+		fmt.Fprintf(&b, "\tt%d [label=\"%s\", fillcolor=\"%s\"];\n", task.ID, label, taskColor(task))
+	}
+	for _, edge := range taskEdges(tasks) {
+		fmt.Fprintf(&b, "\tt%d -> t%d;\n", edge[0], edge[1])
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// TaskGraphMermaid описывает граф тасок выражения в виде блок-схемы Mermaid
+func TaskGraphMermaid(tasks []models.Task) string {
+	var b strings.Builder
+
+	b.WriteString("flowchart TD\n")
+	for _, task := range tasks {
+		label := mermaidEscaper.Replace(taskLabel(task, tasks))
+		fmt.Fprintf(&b, "    t%d[\"%s\"]\n", task.ID, label)
+	}
+	for _, edge := range taskEdges(tasks) {
+		fmt.Fprintf(&b, "    t%d --> t%d\n", edge[0], edge[1])
+	}
+	for _, task := range tasks {
+		fmt.Fprintf(&b, "    style t%d fill:%s\n", task.ID, taskColor(task))
+	}
+
+	return b.String()
+}
+
+// taskLabel подписывает вершину: номер таски, операция с операндами и результат или ошибка.
+// Операнд, который еще ждет результата другой таски, показывается ссылкой на нее
+func taskLabel(task models.Task, tasks []models.Task) string {
+	label := fmt.Sprintf("#%d: %s %s %s",
+		task.ID,
+		operandLabel(task.Arg1, task.PrevTaskID1, tasks),
+		task.Operation,
+		operandLabel(task.Arg2, task.PrevTaskID2, tasks),
+	)
+
+	switch {
+	case task.ErrorMessage != "":
+		label += " (" + task.ErrorMessage + ")"
+	case task.Status == models.StatusResolved:
+		label += " = " + numberKey(task.Result)
+	}
+
+	return label
+}
+
+func operandLabel(arg float64, prevTaskID int, tasks []models.Task) string {
+	if prevTaskID == 0 {
+		return numberKey(arg)
+	}
+
+	for _, task := range tasks {
+		if task.ID == prevTaskID && task.Status == models.StatusResolved && task.ErrorMessage == "" {
+			return numberKey(task.Result)
+		}
+	}
+
+	return fmt.Sprintf("#%d", prevTaskID)
+}
+
+// taskEdges возвращает пары (таска-операнд, зависящая от нее таска)
+func taskEdges(tasks []models.Task) [][2]int {
+	var edges [][2]int
+	for _, task := range tasks {
+		for _, prev := range []int{task.PrevTaskID1, task.PrevTaskID2} {
+			if prev != 0 {
+				edges = append(edges, [2]int{prev, task.ID})
+			}
+		}
+	}
+
+	return edges
+}
+
+// taskColor выбирает цвет вершины по статусу таски. Таска с ошибкой, например делением на ноль,
+// остается в статусе done, но раскрашивается как провалившаяся
+func taskColor(task models.Task) string {
+	if task.ErrorMessage != "" {
+		return statusColors[models.StatusFailed]
+	}
+	if color, ok := statusColors[task.Status]; ok {
+		return color
+	}
+
+	return defaultStatusColor
+}
@@ -0,0 +1,90 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+func graphTasks() []models.Task {
+	return []models.Task{
+		{ID: 1, Arg1: 2, Arg2: 3, Operation: "*", Status: models.StatusResolved, Result: 6},
+		{ID: 2, Arg1: 4, Arg2: 0, Operation: "/", Status: models.StatusFailed, ErrorMessage: "division by zero"},
+		{ID: 3, Arg1: 0, Arg2: 0, PrevTaskID1: 1, PrevTaskID2: 2, Operation: "+", Status: models.StatusWait},
+	}
+}
+
+func TestTaskGraphDOT(t *testing.T) {
+	got := TaskGraphDOT(graphTasks())
+
+	for _, want := range []string{
+		"digraph tasks {",
+		`t1 [label="#1: 2 * 3 = 6", fillcolor="#a9d08e"];`,
+		`t2 [label="#2: 4 / 0 (division by zero)", fillcolor="#f4b183"];`,
+		`t3 [label="#3: 6 + #2", fillcolor="#d9d9d9"];`,
+		"t1 -> t3;",
+		"t2 -> t3;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DOT output must contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestTaskGraphMermaid(t *testing.T) {
+	got := TaskGraphMermaid(graphTasks())
+
+	for _, want := range []string{
+		"flowchart TD",
+		`t1["#1: 2 * 3 = 6"]`,
+		`t3["#3: 6 + #2"]`,
+		"t1 --> t3",
+		"t2 --> t3",
+		"style t2 fill:#f4b183",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Mermaid output must contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestTaskGraph_ResolvedWithError(t *testing.T) {
+	tasks := []models.Task{
+		{ID: 1, Arg1: 4, Arg2: 0, Operation: "/", Status: models.StatusResolved, ErrorMessage: "division by zero"},
+		{ID: 2, Arg1: 0, Arg2: 1, PrevTaskID1: 1, Operation: "+", Status: models.StatusWait},
+	}
+
+	dot := TaskGraphDOT(tasks)
+	for _, want := range []string{
+		`t1 [label="#1: 4 / 0 (division by zero)", fillcolor="#f4b183"];`,
+		`t2 [label="#2: #1 + 1", fillcolor="#d9d9d9"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output must contain %q, got:\n%s", want, dot)
+		}
+	}
+
+	mermaid := TaskGraphMermaid(tasks)
+	if want := "style t1 fill:#f4b183"; !strings.Contains(mermaid, want) {
+		t.Errorf("Mermaid output must contain %q, got:\n%s", want, mermaid)
+	}
+}
+
+func TestTaskGraph_EscapesLabels(t *testing.T) {
+	tasks := []models.Task{
+		{ID: 1, Arg1: 1, Arg2: 2, Operation: "+", Status: models.StatusFailed, ErrorMessage: "bad \"arg\" C:\\tmp\nretry"},
+	}
+
+	dot := TaskGraphDOT(tasks)
+	want := `t1 [label="#1: 1 + 2 (bad \"arg\" C:\\tmp\nretry)"`
+	if !strings.Contains(dot, want) {
+		t.Errorf("DOT output must contain %q, got:\n%s", want, dot)
+	}
+
+	mermaid := TaskGraphMermaid(tasks)
+	want = `t1["#1: 1 + 2 (bad #quot;arg#quot; C:\tmp<br/>retry)"]`
+	if !strings.Contains(mermaid, want) {
+		t.Errorf("Mermaid output must contain %q, got:\n%s", want, mermaid)
+	}
+}
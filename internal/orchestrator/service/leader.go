@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// LeaderLease - имя аренды, которую держит ведущий оркестратор
+const LeaderLease = "orchestrator-leader"
+
+// LeaderElector выбирает ведущего среди оркестраторов, работающих с одной базой.
+// Ведущим становится тот, кто захватил аренду в таблице leases; он регулярно ее продлевает.
+// Если ведущий перестает продлевать аренду, после ее истечения ведущим становится другой экземпляр
+type LeaderElector struct {
+	Repo *repository.ExpressionModel
+
+	// ID - идентификатор экземпляра оркестратора
+	ID string
+
+	// TTL - на сколько захватывается аренда
+	TTL time.Duration
+
+	// RenewInterval - как часто экземпляр пытается захватить или продлить аренду. Должен быть заметно меньше TTL
+	RenewInterval time.Duration
+
+	leader atomic.Bool
+}
+
+// DefaultInstanceID собирает ID экземпляра оркестратора из имени хоста и PID процесса
+func DefaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "orchestrator"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// IsLeader сообщает, является ли экземпляр ведущим
+func (l *LeaderElector) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Run участвует в выборах, пока не отменен ctx. Пока экземпляр ведущий, работают jobs;
+// при потере аренды их контекст отменяется. При завершении ведущий освобождает аренду
+func (l *LeaderElector) Run(ctx context.Context, jobs ...func(ctx context.Context)) {
+	ticker := time.NewTicker(l.RenewInterval)
+	defer ticker.Stop()
+
+	var (
+		stopJobs   context.CancelFunc
+		wg         sync.WaitGroup
+		leaseUntil time.Time
+	)
+
+	stepDown := func(reason string) {
+		if stopJobs == nil {
+			return
+		}
+		stopJobs()
+		wg.Wait()
+		stopJobs = nil
+		l.leader.Store(false)
+		log.Printf("orchestrator %s is no longer the leader: %s", l.ID, reason)
+	}
+
+	for {
+		now := time.Now()
+		acquired, err := l.Repo.AcquireLease(LeaderLease, l.ID, l.TTL)
+
+		switch {
+		case err != nil:
+			log.Printf("leader election: %v", err)
+			// пока аренда не истекла, никто другой не может стать ведущим, поэтому работу можно продолжать
+			if now.After(leaseUntil) {
+				stepDown("lease expired")
+			}
+		case acquired:
+			leaseUntil = now.Add(l.TTL)
+			if stopJobs == nil {
+				var jobsCtx context.Context
+				jobsCtx, stopJobs = context.WithCancel(ctx)
+				l.leader.Store(true)
+				log.Printf("orchestrator %s became the leader", l.ID)
+
+				for _, job := range jobs {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						job(jobsCtx)
+					}()
+				}
+			}
+		default:
+			stepDown("lease is held by another instance")
+		}
+
+		select {
+		case <-ctx.Done():
+			if stopJobs != nil {
+				stepDown("shutting down")
+				if err := l.Repo.ReleaseLease(LeaderLease, l.ID); err != nil {
+					log.Printf("leader election: %v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
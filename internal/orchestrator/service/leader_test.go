@@ -0,0 +1,86 @@
+package orchestrator
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startInstance запускает в процессе экземпляр оркестратора со своим подключением к общей базе.
+// jobs считает, сколько фоновых задач экземпляра работает прямо сейчас
+func startInstance(t *testing.T, dbPath, id string) (*LeaderElector, *repository.ExpressionModel, *atomic.Int32, context.CancelFunc) {
+	db, err := repository.InitDB(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := repository.NewExpressionModel(db)
+	elector := &LeaderElector{Repo: repo, ID: id, TTL: 300 * time.Millisecond, RenewInterval: 50 * time.Millisecond}
+
+	jobs := &atomic.Int32{}
+	job := func(ctx context.Context) {
+		jobs.Add(1)
+		defer jobs.Add(-1)
+		<-ctx.Done()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.Run(ctx, job)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return elector, repo, jobs, cancel
+}
+
+func TestLeaderElector_FailoverOnShutdown(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	first, _, firstJobs, stopFirst := startInstance(t, dbPath, "first")
+	require.Eventually(t, first.IsLeader, time.Second, 10*time.Millisecond)
+
+	second, repo, secondJobs, _ := startInstance(t, dbPath, "second")
+
+	time.Sleep(200 * time.Millisecond)
+	assert.False(t, second.IsLeader(), "only one instance can lead")
+	assert.Equal(t, int32(1), firstJobs.Load())
+	assert.Equal(t, int32(0), secondJobs.Load(), "background jobs run only on the leader")
+
+	stopFirst()
+
+	require.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return secondJobs.Load() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(0), firstJobs.Load())
+
+	holder, err := repo.LeaseHolder(LeaderLease)
+	require.NoError(t, err)
+	assert.Equal(t, "second", holder)
+}
+
+func TestLeaderElector_FailoverOnLeaseExpiry(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	first, firstRepo, firstJobs, _ := startInstance(t, dbPath, "first")
+	require.Eventually(t, first.IsLeader, time.Second, 10*time.Millisecond)
+
+	second, _, secondJobs, _ := startInstance(t, dbPath, "second")
+
+	// ведущий теряет связь с базой и больше не может продлевать аренду
+	require.NoError(t, firstRepo.DB.Close())
+
+	require.Eventually(t, func() bool { return !first.IsLeader() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(0), firstJobs.Load(), "jobs must stop once the lease has expired")
+
+	require.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return secondJobs.Load() == 1 }, time.Second, 10*time.Millisecond)
+}
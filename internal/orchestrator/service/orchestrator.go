@@ -1,32 +1,48 @@
 package orchestrator
 
 import (
+	"time"
+
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
 )
 
 // Calc вызывает токенизацию выражения, записывает его в RPN. а затем в параллельных горутинах подсчитывает значения выражений в скобках
-func Calc(stringExpression string, id int, taskRepo *repository.ExpressionModel) error {
+func Calc(stringExpression string, id int, taskRepo *repository.ExpressionModel) (models.ParseStats, error) {
+	return CalcWithDurations(stringExpression, id, taskRepo, OperationDurations())
+}
+
+// CalcWithDurations работает как Calc, но считает критические пути тасок по переданному времени операций,
+// а не по конфигурации. Нужен симулятору, который проигрывает сценарий со своими длительностями
+func CalcWithDurations(stringExpression string, id int, taskRepo *repository.ExpressionModel, durations map[string]time.Duration) (models.ParseStats, error) {
 	taskRepo.Mu.Lock()
 	defer taskRepo.Mu.Unlock()
 
 	expression, err := tokenize(stringExpression)
 	if err != nil {
-		return err
+		return models.ParseStats{}, err
 	}
 
 	if len(expression) == 0 {
-		return models.ErrorEmptyExpression
+		return models.ParseStats{}, models.ErrorEmptyExpression
 	}
 
 	reversePolishNotation, err := toReversePolishNotation(expression)
 	if err != nil {
-		return err
+		return models.ParseStats{}, err
 	}
 
-	parseRPN(reversePolishNotation, id, taskRepo)
+	tasks, stats, err := planTasks(reversePolishNotation)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, task := range tasks {
+		task.ExpressionID = id
+	}
+	assignCriticalPaths(tasks, durations)
 
-	return nil
+	return stats, insertPlannedTasks(tasks, taskRepo.InsertTask)
 }
 
 // NewTask создает экземпляр структуры Task
@@ -1,7 +1,7 @@
 package orchestrator
 
 import (
-	"database/sql"
+	"path/filepath"
 	"testing"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
@@ -10,39 +10,12 @@ import (
 )
 
 func TestCalc(t *testing.T) {
-	db, err := sql.Open("sqlite", ":memory:")
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
 	if err != nil {
-		t.Fatalf("Failed to open in-memory DB: %v", err)
+		t.Fatalf("Failed to init DB: %v", err)
 	}
 	defer db.Close()
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS expressions (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        user_id INTEGER,
-        expression TEXT NOT NULL,
-        status TEXT NOT NULL,
-        result FLOAT64 DEFAULT 0,
-        error_message TEXT DEFAULT ""
-    );`)
-	if err != nil {
-		t.Fatalf("Failed to create expressions table: %v", err)
-	}
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS tasks (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        expressionID INTEGER NOT NULL,
-        arg1 TEXT NOT NULL,
-        arg2 TEXT NOT NULL,
-        prev_task_id1 INTEGER DEFAULT 0,
-        prev_task_id2 INTEGER DEFAULT 0,
-        operation TEXT NOT NULL,
-        status TEXT,
-        result FLOAT,
-        error_message TEXT DEFAULT ""
-    );`)
-	if err != nil {
-		t.Fatalf("Failed to create tasks table: %v", err)
-	}
-
 	repo := &repository.ExpressionModel{DB: db}
 
 	tests := []struct {
@@ -74,7 +47,7 @@ func TestCalc(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := Calc(tt.expression, 1, repo)
+			_, err := Calc(tt.expression, 1, repo)
 			if (err != nil && tt.expectError == nil) || (err == nil && tt.expectError != nil) {
 				t.Errorf("Unexpected error: got %v, want %v", err, tt.expectError)
 			} else if err != nil && tt.expectError != nil && err.Error() != tt.expectError.Error() {
@@ -83,3 +56,31 @@ func TestCalc(t *testing.T) {
 		})
 	}
 }
+
+func TestCountTasks(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   int
+		wantErr    bool
+	}{
+		{expression: "2", expected: 0},
+		{expression: "2+2", expected: 1},
+		{expression: "(1+2)*(3-4)/5", expected: 4},
+		{expression: "(1+2)*(1+2)", expected: 2},
+		{expression: "(1+2)*(2+1)", expected: 3},
+		{expression: "", wantErr: true},
+		{expression: "2@2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			count, err := CountTasks(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if count != tt.expected {
+				t.Errorf("Task count mismatch: got %d, want %d", count, tt.expected)
+			}
+		})
+	}
+}
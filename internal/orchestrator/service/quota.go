@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// CountTasks возвращает количество тасок, на которые Calc разобьет выражение, с учетом переиспользования одинаковых подвыражений
+func CountTasks(stringExpression string) (int, error) {
+	expression, err := tokenize(stringExpression)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(expression) == 0 {
+		return 0, models.ErrorEmptyExpression
+	}
+
+	reversePolishNotation, err := toReversePolishNotation(expression)
+	if err != nil {
+		return 0, err
+	}
+
+	_, stats, err := planTasks(reversePolishNotation)
+	if err != nil {
+		return 0, err
+	}
+
+	return stats.Tasks, nil
+}
+
+// InsertWithinQuota сохраняет выражение из taskCount тасок, если оно не превысит лимиты пользователя.
+// Потребление пересчитывается в одной транзакции с записью выражения, поэтому параллельные запросы не обходят
+// лимиты. Таски выражения резервируются до вызова ReleaseReservedTasks после их записи.
+// Возвращает ID выражения или описание нарушенного лимита, если выражение принять нельзя
+func InsertWithinQuota(exprRepo *repository.ExpressionModel, expr *models.Expression, taskCount int, defaults models.Quota) (int, *models.QuotaError, error) {
+	quota, err := exprRepo.GetUserQuota(expr.UserID, defaults)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if quota.MaxTasksPerExpression > 0 && taskCount > quota.MaxTasksPerExpression {
+		return 0, newQuotaError("max_tasks_per_expression", quota.MaxTasksPerExpression, 0, taskCount), nil
+	}
+
+	if quota.MaxActiveExpressions == 0 && quota.MaxQueuedTasks == 0 {
+		id, err := exprRepo.InsertExpression(expr)
+		return id, nil, err
+	}
+
+	var quotaErr *models.QuotaError
+	id, err := exprRepo.InsertExpressionWithinQuota(expr, taskCount, func(usage models.QuotaUsage) bool {
+		quotaErr = checkUsage(quota, usage, taskCount)
+		return quotaErr == nil
+	})
+	if quotaErr != nil {
+		return 0, quotaErr, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return id, nil, nil
+}
+
+// checkUsage проверяет, что при потреблении usage выражение из taskCount тасок уложится в лимиты quota
+func checkUsage(quota models.Quota, usage models.QuotaUsage, taskCount int) *models.QuotaError {
+	if quota.MaxActiveExpressions > 0 && usage.ActiveExpressions >= quota.MaxActiveExpressions {
+		return newQuotaError("max_active_expressions", quota.MaxActiveExpressions, usage.ActiveExpressions, 1)
+	}
+
+	if quota.MaxQueuedTasks > 0 && usage.QueuedTasks+taskCount > quota.MaxQueuedTasks {
+		return newQuotaError("max_queued_tasks", quota.MaxQueuedTasks, usage.QueuedTasks, taskCount)
+	}
+
+	return nil
+}
+
+func newQuotaError(quota string, limit, usage, requested int) *models.QuotaError {
+	return &models.QuotaError{
+		Error:        "Too many requests",
+		ErrorMessage: models.ErrorQuotaExceeded.Error(),
+		Quota:        quota,
+		Limit:        limit,
+		Usage:        usage,
+		Requested:    requested,
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"strconv"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
-	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
 )
 
 func toReversePolishNotation(expression []models.Token) ([]models.Token, error) {
@@ -57,31 +56,44 @@ func toReversePolishNotation(expression []models.Token) ([]models.Token, error)
 	return reversePolishNotation, nil
 }
 
-func parseRPN(expression []models.Token, exprID int, taskRepo *repository.ExpressionModel) error {
+// parseRPN строит по выражению в RPN граф тасок и записывает их через insert.
+// Структурно одинаковые подвыражения, например (2+3) в "(2+3)*(2+3)", считаются один раз:
+// повторное вхождение ссылается на уже созданную таску через PrevTaskID
+func parseRPN(expression []models.Token, exprID int, insert func(task *models.Task) (int, error)) (models.ParseStats, error) {
 	type StackElement struct {
 		Value  float64
 		TaskID int
 		IsTask bool
+		Key    string
 	}
 
 	var stack []StackElement
+	var stats models.ParseStats
+	tasks := make(map[string]int)
 
 	for _, token := range expression {
 		if token.IsNumber {
 			value, err := strconv.ParseFloat(token.Value, 64)
 			if err != nil {
-				return fmt.Errorf("failed to parse number: %v", err)
+				return stats, fmt.Errorf("failed to parse number: %v", err)
 			}
-			stack = append(stack, StackElement{Value: value})
+			stack = append(stack, StackElement{Value: value, Key: numberKey(value)})
 		} else {
 			if len(stack) < 2 {
-				return fmt.Errorf("not enough operands for operation %s", token.Value)
+				return stats, fmt.Errorf("not enough operands for operation %s", token.Value)
 			}
 
 			right := stack[len(stack)-1]
 			left := stack[len(stack)-2]
 			stack = stack[:len(stack)-2]
 
+			key := subexpressionKey(left.Key, token.Value, right.Key)
+			if taskID, ok := tasks[key]; ok {
+				stats.TasksSaved++
+				stack = append(stack, StackElement{TaskID: taskID, IsTask: true, Key: key})
+				continue
+			}
+
 			task := NewTask(exprID, left.Value, right.Value, token.Value)
 			task.Status = models.StatusWait
 
@@ -97,20 +109,32 @@ func parseRPN(expression []models.Token, exprID int, taskRepo *repository.Expres
 				task.Arg2 = right.Value
 			}
 
-			taskID, err := taskRepo.InsertTask(task)
+			taskID, err := insert(task)
 			if err != nil {
-				return fmt.Errorf("failed to insert task: %v", err)
+				return stats, fmt.Errorf("failed to insert task: %v", err)
 			}
 
-			stack = append(stack, StackElement{TaskID: taskID, IsTask: true})
+			tasks[key] = taskID
+			stats.Tasks++
+			stack = append(stack, StackElement{TaskID: taskID, IsTask: true, Key: key})
 		}
 	}
 
 	if len(stack) != 1 {
-		return fmt.Errorf("invalid expression")
+		return stats, fmt.Errorf("invalid expression")
 	}
 
-	return nil
+	return stats, nil
+}
+
+// numberKey и subexpressionKey задают каноническую запись подвыражения:
+// числа записываются без лишних нулей, каждая операция берется в скобки
+func numberKey(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func subexpressionKey(left, operation, right string) string {
+	return "(" + left + operation + right + ")"
 }
 
 func lastToken(tokens []models.Token) models.Token {
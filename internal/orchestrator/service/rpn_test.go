@@ -1,8 +1,8 @@
 package orchestrator
 
 import (
-	"database/sql"
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
@@ -81,21 +81,12 @@ func TestToReversePolishNotation(t *testing.T) {
 }
 
 func TestParseRPN(t *testing.T) {
-	db, _ := sql.Open("sqlite", ":memory:")
-	db.Exec(`
-        CREATE TABLE tasks (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            expressionID INTEGER NOT NULL,
-            arg1 TEXT NOT NULL,
-            arg2 TEXT NOT NULL,
-            prev_task_id1 INTEGER DEFAULT 0,
-            prev_task_id2 INTEGER DEFAULT 0,
-            operation TEXT NOT NULL,
-            status TEXT,
-            result FLOAT,
-            error_message TEXT DEFAULT ""
-        )
-    `)
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+
 	repo := &repository.ExpressionModel{DB: db}
 
 	tests := []struct {
@@ -124,7 +115,7 @@ func TestParseRPN(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := parseRPN(tt.tokens, 1, repo)
+			_, err := parseRPN(tt.tokens, 1, repo.InsertTask)
 			if (err == nil && tt.err != nil) || (err != nil && tt.err == nil) {
 				t.Errorf("Expected error %v, got %v", tt.err, err)
 			} else if err != nil && tt.err != nil && err.Error() != tt.err.Error() {
@@ -133,3 +124,37 @@ func TestParseRPN(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRPN_CommonSubexpression(t *testing.T) {
+	expression, err := tokenize("(2+3)*(2+3)-(2+3)*(2+3)")
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %v", err)
+	}
+	rpn, err := toReversePolishNotation(expression)
+	if err != nil {
+		t.Fatalf("Failed to convert to RPN: %v", err)
+	}
+
+	var tasks []*models.Task
+	stats, err := parseRPN(rpn, 1, func(task *models.Task) (int, error) {
+		tasks = append(tasks, task)
+		return len(tasks), nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stats.Tasks != 3 || stats.TasksSaved != 4 {
+		t.Fatalf("Stats mismatch: got %+v, want 3 tasks and 4 saved", stats)
+	}
+
+	mul := tasks[1]
+	if mul.Operation != "*" || mul.PrevTaskID1 != 1 || mul.PrevTaskID2 != 1 {
+		t.Errorf("Multiplication must reuse task 1 for both operands: got %+v", mul)
+	}
+
+	sub := tasks[2]
+	if sub.Operation != "-" || sub.PrevTaskID1 != 2 || sub.PrevTaskID2 != 2 {
+		t.Errorf("Subtraction must reuse task 2 for both operands: got %+v", sub)
+	}
+}
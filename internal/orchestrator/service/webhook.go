@@ -0,0 +1,261 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+const (
+	// SignatureHeader - заголовок с HMAC-SHA256 подписью тела уведомления
+	SignatureHeader = "X-Webhook-Signature"
+
+	// DeliveryHeader - заголовок с ID уведомления, по нему получатель отбрасывает повторы
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	// webhookBatchSize - сколько уведомлений доставляется за один проход
+	webhookBatchSize = 100
+
+	// placeholderWebhookSecret - заглушка, которая раньше была значением webhook.SECRET по умолчанию.
+	// Она известна всем, поэтому подписывать ею уведомления нельзя
+	placeholderWebhookSecret = "your_webhook_secret_here"
+)
+
+// WebhookDispatcher доставляет уведомления из исходящей очереди webhook_deliveries. Неудачные попытки
+// повторяются с экспоненциально растущей паузой, пока не кончится MaxAttempts
+type WebhookDispatcher struct {
+	Repo *repository.ExpressionModel
+
+	// Client отправляет уведомления. Если не задан, используется клиент NewWebhookClient
+	Client *http.Client
+
+	// Secret - ключ, которым подписываются уведомления
+	Secret string
+
+	MaxAttempts int
+
+	// Backoff - пауза после первой неудачной попытки. После каждой следующей она удваивается,
+	// но не превышает MaxBackoff, если он задан
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Run раз в interval доставляет накопившиеся уведомления. Работает, пока не отменен ctx.
+// Если ключ подписи не задан, не запускается: уведомления остаются в очереди до перезапуска с ключом
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	if err := d.checkSecret(); err != nil {
+		log.Printf("webhook dispatcher is disabled: %v, set webhook.SECRET", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := d.DeliverPending(ctx, now); err != nil {
+				log.Printf("webhook dispatcher: %v", err)
+			}
+		}
+	}
+}
+
+// DeliverPending делает по одной попытке доставки для каждого уведомления, время которого наступило к now.
+// Возвращает, сколько уведомлений доставлено
+func (d *WebhookDispatcher) DeliverPending(ctx context.Context, now time.Time) (int, error) {
+	if err := d.checkSecret(); err != nil {
+		return 0, err
+	}
+
+	deliveries, err := d.Repo.PendingWebhookDeliveries(now, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		code, deliverErr := d.deliver(ctx, delivery)
+		if deliverErr == nil {
+			if err := d.Repo.MarkWebhookDelivered(delivery.ID, code); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		var nextAttempt *time.Time
+		if attempts := delivery.Attempts + 1; attempts < d.MaxAttempts {
+			next := now.Add(d.backoff(attempts))
+			nextAttempt = &next
+		}
+		log.Printf("failed to deliver webhook ID-%d for expression ID-%d: %v", delivery.ID, delivery.ExpressionID, deliverErr)
+
+		if err := d.Repo.RetryWebhookDelivery(delivery.ID, code, deliverErr.Error(), nextAttempt); err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// deliver отправляет подписанное уведомление и возвращает код ответа. Ответ вне 2xx считается ошибкой
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, SignWebhook(d.Secret, delivery.Payload))
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+
+	client := d.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkSecret проверяет, что уведомления есть чем подписать
+func (d *WebhookDispatcher) checkSecret() error {
+	if d.Secret == "" || d.Secret == placeholderWebhookSecret {
+		return models.ErrorWebhookSecretNotSet
+	}
+	return nil
+}
+
+// backoff возвращает паузу перед следующей попыткой после attempts неудачных
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if d.MaxBackoff > 0 && wait >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// SignWebhook возвращает подпись тела уведомления в формате sha256=<hex HMAC-SHA256>
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateCallbackURL проверяет, что на адрес можно отправлять уведомления: это абсолютный http или https URL,
+// и его хост указывает только на публичные адреса. Иначе любой пользователь мог бы заставить оркестратор
+// отправлять запросы на localhost, во внутреннюю сеть или в сервис метаданных облака
+func ValidateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return models.ErrorInvalidCallbackURL
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return models.ErrorCallbackHostNotAllowed
+		}
+		return nil
+	}
+
+	addrs, err := lookupIPAddr(context.Background(), host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: cannot resolve host %s", models.ErrorInvalidCallbackURL, host)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return models.ErrorCallbackHostNotAllowed
+		}
+	}
+	return nil
+}
+
+// lookupIPAddr разрешает имя хоста в адреса
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// defaultWebhookClient доставляет уведомления, если у WebhookDispatcher не задан Client
+var defaultWebhookClient = NewWebhookClient(5 * time.Second)
+
+// NewWebhookClient возвращает HTTP клиент, который соединяется только с публичными адресами.
+// Адрес проверяется в момент соединения, поэтому уведомление не уйдет во внутреннюю сеть,
+// даже если DNS получателя изменился после ValidateCallbackURL или получатель ответил редиректом
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// dialPublicOnly запрещает соединение с непубличным адресом
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", models.ErrorCallbackHostNotAllowed, host)
+	}
+	return nil
+}
+
+// reservedNets - диапазоны, которые не покрываются проверками net.IP, но тоже не ведут в интернет
+var reservedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+// isPublicIP сообщает, что адрес не loopback, не из частной сети, не link-local и не служебный
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, reserved := range reservedNets {
+		if reserved.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
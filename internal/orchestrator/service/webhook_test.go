@@ -0,0 +1,203 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhookRepo(t *testing.T) *repository.ExpressionModel {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return repository.NewExpressionModel(db)
+}
+
+func TestWebhookDispatcher_Deliver(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := newWebhookRepo(t)
+	expr := &models.Expression{UserID: 1, Expression: "2*3", CallbackURL: receiver.URL}
+	_, err := repo.InsertExpression(expr)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateExpressionResult(expr.ID, 6, ""))
+
+	dispatcher := &WebhookDispatcher{Repo: repo, Client: receiver.Client(), Secret: "secret", MaxAttempts: 3, Backoff: time.Second}
+	delivered, err := dispatcher.DeliverPending(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	req, body := <-received, <-bodies
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, SignWebhook("secret", body), req.Header.Get(SignatureHeader))
+
+	var payload models.WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, expr.ID, payload.ExpressionID)
+	assert.Equal(t, models.StatusResolved, payload.Status)
+	assert.Equal(t, 6.0, payload.Result)
+
+	deliveries, err := repo.ListWebhookDeliveries(expr.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseCode)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+func TestWebhookDispatcher_RequiresSecret(t *testing.T) {
+	requests := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer receiver.Close()
+
+	repo := newWebhookRepo(t)
+	expr := &models.Expression{UserID: 1, Expression: "2*3", CallbackURL: receiver.URL}
+	_, err := repo.InsertExpression(expr)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateExpressionResult(expr.ID, 6, ""))
+
+	for _, secret := range []string{"", placeholderWebhookSecret} {
+		dispatcher := &WebhookDispatcher{Repo: repo, Secret: secret, MaxAttempts: 3, Backoff: time.Second}
+		_, err := dispatcher.DeliverPending(context.Background(), time.Now())
+		assert.ErrorIs(t, err, models.ErrorWebhookSecretNotSet)
+	}
+	assert.Zero(t, requests, "webhooks must not be signed with a missing or publicly known key")
+
+	deliveries, err := repo.ListWebhookDeliveries(expr.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+}
+
+func TestWebhookDispatcher_RetryWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	repo := newWebhookRepo(t)
+	expr := &models.Expression{UserID: 1, Expression: "1/0", CallbackURL: receiver.URL}
+	_, err := repo.InsertExpression(expr)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateExpressionResult(expr.ID, 0, "division by zero"))
+
+	dispatcher := &WebhookDispatcher{Repo: repo, Client: receiver.Client(), Secret: "secret", MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 90 * time.Second}
+	now := time.Now()
+
+	_, err = dispatcher.DeliverPending(context.Background(), now)
+	require.NoError(t, err)
+
+	deliveries, err := repo.ListWebhookDeliveries(expr.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseCode)
+	require.NotNil(t, deliveries[0].NextAttemptAt)
+	assert.Equal(t, now.Add(time.Second).UnixMilli(), deliveries[0].NextAttemptAt.UnixMilli())
+
+	_, err = dispatcher.DeliverPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load(), "retry must wait for the backoff")
+
+	now = now.Add(time.Second)
+	_, err = dispatcher.DeliverPending(context.Background(), now)
+	require.NoError(t, err)
+
+	deliveries, err = repo.ListWebhookDeliveries(expr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(2*time.Second).UnixMilli(), deliveries[0].NextAttemptAt.UnixMilli(), "backoff doubles")
+
+	_, err = dispatcher.DeliverPending(context.Background(), now.Add(2*time.Second))
+	require.NoError(t, err)
+
+	deliveries, err = repo.ListWebhookDeliveries(expr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	dispatcher := &WebhookDispatcher{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, dispatcher.backoff(1))
+	assert.Equal(t, 2*time.Second, dispatcher.backoff(2))
+	assert.Equal(t, 4*time.Second, dispatcher.backoff(3))
+	assert.Equal(t, 5*time.Second, dispatcher.backoff(4))
+	assert.Equal(t, 5*time.Second, dispatcher.backoff(40))
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	lookup := lookupIPAddr
+	defer func() { lookupIPAddr = lookup }()
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+		case "localhost":
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+		case "internal.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+
+	assert.NoError(t, ValidateCallbackURL("https://example.com/hook"))
+	assert.NoError(t, ValidateCallbackURL("http://203.0.113.10:9000/hook"))
+	assert.ErrorIs(t, ValidateCallbackURL("ftp://example.com"), models.ErrorInvalidCallbackURL)
+	assert.ErrorIs(t, ValidateCallbackURL("/relative"), models.ErrorInvalidCallbackURL)
+	assert.ErrorIs(t, ValidateCallbackURL("http://"), models.ErrorInvalidCallbackURL)
+	assert.ErrorIs(t, ValidateCallbackURL("http://unknown.invalid/hook"), models.ErrorInvalidCallbackURL)
+
+	for _, raw := range []string{
+		"http://localhost:9000",
+		"http://127.0.0.1:9000",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://internal.example.com/hook",
+	} {
+		assert.ErrorIs(t, ValidateCallbackURL(raw), models.ErrorCallbackHostNotAllowed, raw)
+	}
+}
+
+func TestNewWebhookClient_RefusesPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook client must not connect to a loopback address")
+	}))
+	defer receiver.Close()
+
+	_, err := NewWebhookClient(time.Second).Post(receiver.URL, "application/json", nil)
+	assert.ErrorIs(t, err, models.ErrorCallbackHostNotAllowed)
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RegisterAgent регистрирует агента при запуске: его ID, хост, версию, количество воркеров и поддерживаемые операции.
+// В ответ агент узнает, как часто ему нужно присылать heartbeat
+func (ts *TaskServer) RegisterAgent(ctx context.Context, req *proto.AgentInfo) (*proto.RegisterAgentResponse, error) {
+	if ts.Agents == nil {
+		return nil, status.Error(codes.Unimplemented, "agent registry is not available")
+	}
+
+	if req.AgentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "agent ID is required")
+	}
+
+	err := ts.Agents.RegisterAgent(&models.Agent{
+		ID:         req.AgentId,
+		Hostname:   req.Hostname,
+		Version:    req.Version,
+		Workers:    int(req.Workers),
+		Operations: req.Operations,
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, status.Errorf(codes.Internal, "failed to register agent: %v", err)
+	}
+
+	log.Printf("agent %s registered: host %s, version %s, %d workers, operations %v",
+		req.AgentId, req.Hostname, req.Version, req.Workers, req.Operations)
+
+	return &proto.RegisterAgentResponse{HeartbeatIntervalMs: ts.heartbeatInterval().Milliseconds()}, nil
+}
+
+// AgentHeartbeat отмечает, что агент на связи. Незарегистрированный агент получает NotFound и должен зарегистрироваться заново
+func (ts *TaskServer) AgentHeartbeat(ctx context.Context, req *proto.AgentHeartbeatRequest) (*proto.AgentHeartbeatResponse, error) {
+	if ts.Agents == nil {
+		return nil, status.Error(codes.Unimplemented, "agent registry is not available")
+	}
+
+	err := ts.Agents.TouchAgent(req.AgentId)
+	if errors.Is(err, models.ErrorAgentNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, status.Errorf(codes.Internal, "failed to update agent: %v", err)
+	}
+
+	return &proto.AgentHeartbeatResponse{}, nil
+}
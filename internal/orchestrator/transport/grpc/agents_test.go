@@ -0,0 +1,79 @@
+package orchestrator_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegisterAgent_TracksTasksAndLiveness(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	_, err = client.AgentHeartbeat(context.Background(), &proto.AgentHeartbeatRequest{AgentId: "agent-1"})
+	assert.Equal(t, codes.NotFound, status.Code(err), "unregistered agent must register first")
+
+	resp, err := client.RegisterAgent(context.Background(), &proto.AgentInfo{
+		AgentId:    "agent-1",
+		Hostname:   "host-1",
+		Version:    "1.2.3",
+		Workers:    4,
+		Operations: []string{"+", "-"},
+	})
+	require.NoError(t, err)
+	assert.Greater(t, resp.HeartbeatIntervalMs, int64(0))
+
+	_, err = client.AgentHeartbeat(context.Background(), &proto.AgentHeartbeatRequest{AgentId: "agent-1"})
+	require.NoError(t, err)
+
+	insertExpression(t, ts, "1+2")
+	task, err := client.ReceiveTask(context.Background(), &proto.GetTaskRequest{AgentId: "agent-1"})
+	require.NoError(t, err)
+
+	agents, err := ts.exprRepo.ListAgents(time.Minute)
+	require.NoError(t, err)
+	require.Len(t, agents, 1)
+	assert.Equal(t, "host-1", agents[0].Hostname)
+	assert.Equal(t, "1.2.3", agents[0].Version)
+	assert.Equal(t, 4, agents[0].Workers)
+	assert.Equal(t, []string{"+", "-"}, agents[0].Operations)
+	assert.Equal(t, models.AgentStateBusy, agents[0].State)
+	assert.Equal(t, []int{int(task.Id)}, agents[0].CurrentTasks)
+
+	_, err = client.SubmitTaskResult(context.Background(), &proto.SubmitTaskResultRequest{TaskId: task.Id, Result: 3})
+	require.NoError(t, err)
+
+	agents, err = ts.exprRepo.ListAgents(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, models.AgentStateIdle, agents[0].State)
+	assert.Empty(t, agents[0].CurrentTasks)
+	assert.Equal(t, 1, agents[0].Throughput)
+}
+
+func TestRegisterAgent_RequiresID(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = proto.NewTaskServiceClient(conn).RegisterAgent(context.Background(), &proto.AgentInfo{Hostname: "host-1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,114 @@
+package orchestrator_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/mock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	orchestratorGrpc "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/grpc"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// setupMemoryServer поднимает gRPC сервер поверх хранилища в памяти и возвращает клиента к нему
+func setupMemoryServer(t *testing.T) (*mock.MemoryRepository, proto.TaskServiceClient) {
+	repo := mock.NewMemoryRepository()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	proto.RegisterTaskServiceServer(server, &orchestratorGrpc.TaskServer{ExprRepo: repo, Agents: repo})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return repo, proto.NewTaskServiceClient(conn)
+}
+
+func TestMemoryRepository_FullExpressionLifecycle(t *testing.T) {
+	repo, client := setupMemoryServer(t)
+
+	exprID, err := repo.Insert("(2+3)*4", 1)
+	require.NoError(t, err)
+	sumID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 3, Operation: "+", Status: models.StatusWait})
+	repo.InsertTask(&models.Task{ExpressionID: exprID, PrevTaskID1: sumID, Arg2: 4, Operation: "*", Status: models.StatusWait})
+
+	ctx := context.Background()
+	for _, want := range []struct {
+		arg1, arg2, result float64
+	}{{2, 3, 5}, {5, 4, 20}} {
+		task, err := client.ReceiveTask(ctx, &proto.GetTaskRequest{AgentId: "agent-1"})
+		require.NoError(t, err)
+		assert.Equal(t, want.arg1, task.Arg1)
+		assert.Equal(t, want.arg2, task.Arg2)
+
+		_, err = client.SubmitTaskResult(ctx, &proto.SubmitTaskResultRequest{TaskId: task.Id, Result: want.result})
+		require.NoError(t, err)
+	}
+
+	_, err = client.ReceiveTask(ctx, &proto.GetTaskRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	expr, err := repo.GetExpression(exprID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusResolved, expr.Status)
+	assert.Equal(t, float64(20), expr.Result)
+}
+
+func TestMemoryRepository_PausedQueue(t *testing.T) {
+	repo, client := setupMemoryServer(t)
+
+	exprID, _ := repo.Insert("2+2", 1)
+	repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+	require.NoError(t, repo.SetQueueState(models.QueueState{Paused: true}))
+
+	_, err := client.ReceiveTask(context.Background(), &proto.GetTaskRequest{AgentId: "agent-1"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.NoError(t, repo.SetQueueState(models.QueueState{}))
+	task, err := client.ReceiveTask(context.Background(), &proto.GetTaskRequest{AgentId: "agent-1"})
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), task.Arg1)
+}
+
+// brokenStream - поток, в который не удается отправить ни одной таски
+type brokenStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *brokenStream) Context() context.Context { return s.ctx }
+
+func (s *brokenStream) Send(*proto.Task) error { return errors.New("connection reset") }
+
+func TestStreamTasks_RequeueOnSendError(t *testing.T) {
+	repo := mock.NewMemoryRepository()
+	server := &orchestratorGrpc.TaskServer{ExprRepo: repo, Agents: repo}
+
+	exprID, _ := repo.Insert("2+2", 1)
+	taskID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	err := server.StreamTasks(&proto.ReceiveTasksRequest{AgentId: "agent-1", Capacity: 1}, &brokenStream{ctx: context.Background()})
+	require.Error(t, err)
+
+	status, _, err := repo.GetTaskStatus(taskID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusWait, status, "task that never reached the agent goes back to the queue")
+
+	task, _, err := repo.GetTask(models.TaskFilter{AgentID: "agent-2"})
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, "agent-2", task.AgentID)
+}
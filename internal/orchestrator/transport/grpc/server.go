@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
 	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 )
@@ -26,7 +29,13 @@ func RunGRPCOrchestrator(exprRepo models.ExpressionRepository) error {
 		grpc.MaxRecvMsgSize(10*1024*1024),
 		grpc.MaxSendMsgSize(10*1024*1024),
 	)
-	proto.RegisterTaskServiceServer(s, newTaskServer(exprRepo))
+	taskServer := newTaskServer(exprRepo)
+	taskServer.HeartbeatInterval = time.Duration(viper.GetInt("agent.HEARTBEAT_INTERVAL_MS")) * time.Millisecond
+	taskServer.HeartbeatTimeout = time.Duration(viper.GetInt("agent.HEARTBEAT_TIMEOUT_MS")) * time.Millisecond
+
+	WatchAgentConfig(taskServer)
+
+	proto.RegisterTaskServiceServer(s, taskServer)
 	log.Printf("gRPC server listening on %s", addr)
 
 	if err := s.Serve(lis); err != nil {
@@ -34,3 +43,27 @@ func RunGRPCOrchestrator(exprRepo models.ExpressionRepository) error {
 	}
 	return nil
 }
+
+// WatchAgentConfig следит за файлом конфигурации и при каждом его изменении рассылает агентам
+// длительности операций duration.*, так что их можно поменять без перезапуска агентов.
+// Переменные окружения имеют приоритет над файлом, поэтому заданные через них значения не меняются
+func WatchAgentConfig(ts *TaskServer) {
+	if viper.ConfigFileUsed() == "" {
+		return
+	}
+
+	viper.OnConfigChange(func(fsnotify.Event) {
+		log.Print("config file changed, sending operation durations to agents")
+		ts.UpdateAgentConfig(agentConfig())
+	})
+	viper.WatchConfig()
+}
+
+// agentConfig возвращает настройки агентов из конфигурации оркестратора
+func agentConfig() *proto.AgentConfig {
+	durations := make(map[string]int64)
+	for operation, duration := range orchestrator.OperationDurations() {
+		durations[operation] = duration.Milliseconds()
+	}
+	return &proto.AgentConfig{OperationDurationMs: durations}
+}
@@ -1,7 +1,10 @@
 package orchestrator_test
 
 import (
+	"context"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -33,3 +36,29 @@ func TestRunGRPCOrchestrator_Failure(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to listen")
 }
+
+func TestWatchAgentConfig(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("duration.TIME_ADDITION_MS=100\n"), 0644))
+
+	viper.SetConfigFile(path)
+	require.NoError(t, viper.ReadInConfig())
+	defer viper.Reset()
+
+	orchestratorGRPC.WatchAgentConfig(ts.taskServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, messages := openSession(t, ts, ctx, 1)
+	nextMessage(t, messages)
+
+	require.NoError(t, os.WriteFile(path, []byte("duration.TIME_ADDITION_MS=10\n"), 0644))
+
+	config := nextMessage(t, messages).GetConfig()
+	require.NotNil(t, config)
+	assert.Equal(t, int64(10), config.OperationDurationMs["+"])
+}
@@ -0,0 +1,271 @@
+package orchestrator
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultHeartbeatInterval - как часто оркестратор и агент обмениваются heartbeat'ами в сессии
+	DefaultHeartbeatInterval = time.Second
+
+	// DefaultHeartbeatTimeout - через сколько без сообщений от агента сессия считается оборванной
+	DefaultHeartbeatTimeout = 5 * time.Second
+)
+
+// agentSession - двунаправленная сессия с агентом. В отличие от StreamTasks, результаты приходят
+// в ту же сессию, поэтому оркестратор точно знает, какие таски сейчас у агента
+type agentSession struct {
+	*dispatchStream
+
+	filter   models.TaskFilter
+	config   chan *proto.AgentConfig
+	lastSeen time.Time
+	seenMu   sync.Mutex
+}
+
+func (s *agentSession) touch(now time.Time) {
+	s.seenMu.Lock()
+	defer s.seenMu.Unlock()
+	s.lastSeen = now
+}
+
+func (s *agentSession) silentFor(now time.Time) time.Duration {
+	s.seenMu.Lock()
+	defer s.seenMu.Unlock()
+	return now.Sub(s.lastSeen)
+}
+
+func (s *agentSession) tasks() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]int, 0, len(s.inFlight))
+	for id := range s.inFlight {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// AgentSession обслуживает двунаправленную сессию агента. Первым сообщением агент сообщает свою capacity,
+// дальше оркестратор присылает таски, heartbeat'ы, отмены снятых с очереди тасок и обновления настроек,
+// а агент - heartbeat'ы и результаты. Как только сессия обрывается или агент перестает присылать heartbeat'ы,
+// все незавершенные таски агента возвращаются в очередь
+func (ts *TaskServer) AgentSession(stream grpc.BidiStreamingServer[proto.AgentMessage, proto.OrchestratorMessage]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	hello := first.GetHello()
+	if hello == nil || hello.Capacity <= 0 {
+		return status.Error(codes.InvalidArgument, "session must start with hello carrying positive capacity")
+	}
+
+	capacity := int(hello.Capacity)
+	if capacity > maxBatchSize {
+		capacity = maxBatchSize
+	}
+
+	session := &agentSession{
+		dispatchStream: newDispatchStream(capacity),
+		filter:         models.TaskFilter{AgentID: hello.AgentId, Operations: hello.Operations},
+		config:         make(chan *proto.AgentConfig, 1),
+		lastSeen:       time.Now(),
+	}
+	ts.addSession(session)
+	defer ts.closeSession(session)
+
+	interval, timeout := ts.heartbeatInterval(), ts.heartbeatTimeout()
+
+	config := &proto.AgentConfig{HeartbeatIntervalMs: interval.Milliseconds()}
+	if current := ts.currentAgentConfig(); current != nil {
+		config.OperationDurationMs = current.OperationDurationMs
+	}
+
+	err = stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Config{Config: config}})
+	if err != nil {
+		return err
+	}
+
+	received := make(chan error, 1)
+	go func() {
+		received <- ts.receiveSession(stream, session)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx := stream.Context()
+
+	for {
+		ready := ts.ExprRepo.TasksReady()
+
+		for session.free() > 0 {
+			task, id, err := ts.ExprRepo.GetTask(session.filter)
+			if errors.Is(err, models.ErrorQueuePaused) {
+				break
+			}
+			if err != nil {
+				log.Println("Failed to get task:", err)
+				return status.Errorf(codes.Internal, "failed to get task: %v", err)
+			}
+
+			if task == nil {
+				break
+			}
+
+			ts.assign(id, session.dispatchStream)
+
+			err = stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Assignment{
+				Assignment: toProtoTask(task),
+			}})
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ready:
+		case <-session.wake:
+		case cfg := <-session.config:
+			if err := stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Config{Config: cfg}}); err != nil {
+				return err
+			}
+		case now := <-ticker.C:
+			if silent := session.silentFor(now); silent > timeout {
+				log.Printf("agent session is dead: no messages for %v", silent)
+				return status.Error(codes.DeadlineExceeded, "heartbeat timeout")
+			}
+
+			if err := ts.cancelWithdrawn(stream, session); err != nil {
+				return err
+			}
+
+			err := stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Heartbeat{
+				Heartbeat: &proto.Heartbeat{SentAtMs: now.UnixMilli()},
+			}})
+			if err != nil {
+				return err
+			}
+		case err := <-received:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// receiveSession читает сообщения агента: heartbeat'ы продлевают сессию, результаты записываются в базу
+func (ts *TaskServer) receiveSession(stream grpc.BidiStreamingServer[proto.AgentMessage, proto.OrchestratorMessage], session *agentSession) error {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		session.touch(time.Now())
+
+		if msg.GetHeartbeat() != nil && session.filter.AgentID != "" && ts.Agents != nil {
+			if err := ts.Agents.TouchAgent(session.filter.AgentID); err != nil {
+				log.Printf("failed to update agent %s: %v", session.filter.AgentID, err)
+			}
+		}
+
+		if result := msg.GetResult(); result != nil {
+			err := ts.ExprRepo.UpdateTaskResult(int(result.TaskId), result.Result, result.ErrorMessage)
+			if err != nil {
+				log.Printf("failed to update result of task ID-%d: %v", result.TaskId, err)
+			}
+			ts.releaseTask(int(result.TaskId))
+		}
+	}
+}
+
+// cancelWithdrawn сообщает агенту об отмене тасок, снятых с очереди, например, по дедлайну выражения
+func (ts *TaskServer) cancelWithdrawn(stream grpc.BidiStreamingServer[proto.AgentMessage, proto.OrchestratorMessage], session *agentSession) error {
+	for _, taskID := range session.tasks() {
+		taskStatus, _, err := ts.ExprRepo.GetTaskStatus(taskID)
+		if err != nil || taskStatus != models.StatusFailed {
+			continue
+		}
+
+		ts.releaseTask(taskID)
+
+		err = stream.Send(&proto.OrchestratorMessage{Payload: &proto.OrchestratorMessage_Cancellation{
+			Cancellation: &proto.TaskCancellation{TaskId: int32(taskID), Reason: "task withdrawn"},
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateAgentConfig рассылает новые настройки всем агентам с открытой сессией и запоминает их,
+// чтобы длительности операций получили и агенты, которые подключатся позже
+func (ts *TaskServer) UpdateAgentConfig(cfg *proto.AgentConfig) {
+	ts.streamsMu.Lock()
+	defer ts.streamsMu.Unlock()
+
+	ts.agentConfig = cfg
+	for session := range ts.sessions {
+		select {
+		case <-session.config:
+		default:
+		}
+		session.config <- cfg
+	}
+}
+
+func (ts *TaskServer) currentAgentConfig() *proto.AgentConfig {
+	ts.streamsMu.Lock()
+	defer ts.streamsMu.Unlock()
+
+	return ts.agentConfig
+}
+
+func (ts *TaskServer) addSession(session *agentSession) {
+	ts.streamsMu.Lock()
+	defer ts.streamsMu.Unlock()
+
+	if ts.sessions == nil {
+		ts.sessions = make(map[*agentSession]bool)
+	}
+	ts.sessions[session] = true
+}
+
+// closeSession возвращает в очередь все таски, результат которых агент так и не прислал
+func (ts *TaskServer) closeSession(session *agentSession) {
+	ts.streamsMu.Lock()
+	delete(ts.sessions, session)
+	ts.streamsMu.Unlock()
+
+	ts.forgetStream(session.dispatchStream)
+}
+
+func (ts *TaskServer) heartbeatInterval() time.Duration {
+	if ts.HeartbeatInterval > 0 {
+		return ts.HeartbeatInterval
+	}
+	return DefaultHeartbeatInterval
+}
+
+func (ts *TaskServer) heartbeatTimeout() time.Duration {
+	if ts.HeartbeatTimeout > 0 {
+		return ts.HeartbeatTimeout
+	}
+	return DefaultHeartbeatTimeout
+}
@@ -0,0 +1,200 @@
+package orchestrator_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// openSession открывает сессию агента и возвращает канал сообщений оркестратора
+func openSession(t *testing.T, ts *testServer, ctx context.Context, capacity int32) (proto.TaskService_AgentSessionClient, <-chan *proto.OrchestratorMessage) {
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	stream, err := proto.NewTaskServiceClient(conn).AgentSession(ctx)
+	require.NoError(t, err)
+
+	err = stream.Send(&proto.AgentMessage{Payload: &proto.AgentMessage_Hello{Hello: &proto.AgentHello{Capacity: capacity}}})
+	require.NoError(t, err)
+
+	messages := make(chan *proto.OrchestratorMessage, 10)
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+
+	return stream, messages
+}
+
+// nextMessage ждет следующее сообщение оркестратора, пропуская heartbeat'ы
+func nextMessage(t *testing.T, messages <-chan *proto.OrchestratorMessage) *proto.OrchestratorMessage {
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			if msg.GetHeartbeat() != nil {
+				continue
+			}
+			return msg
+		case <-timeout:
+			t.Fatal("Timeout waiting for orchestrator message")
+			return nil
+		}
+	}
+}
+
+func insertExpression(t *testing.T, ts *testServer, expr string) int {
+	exprID, err := ts.exprRepo.Insert(expr, 1)
+	require.NoError(t, err)
+	_, err = orchestrator.Calc(expr, exprID, ts.exprRepo)
+	require.NoError(t, err)
+	return exprID
+}
+
+func TestAgentSession_AssignmentAndResult(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, messages := openSession(t, ts, ctx, 1)
+
+	config := nextMessage(t, messages).GetConfig()
+	require.NotNil(t, config, "session must start with agent config")
+	assert.Greater(t, config.HeartbeatIntervalMs, int64(0))
+
+	exprID := insertExpression(t, ts, "1+2")
+
+	task := nextMessage(t, messages).GetAssignment()
+	require.NotNil(t, task)
+
+	err := stream.Send(&proto.AgentMessage{Payload: &proto.AgentMessage_Result{
+		Result: &proto.SubmitTaskResultRequest{TaskId: task.Id, Result: 3},
+	}})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		expr, err := ts.exprRepo.GetExpression(exprID)
+		return err == nil && expr.Status == models.StatusResolved
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAgentSession_RequeueOnDisconnect(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, messages := openSession(t, ts, ctx, 1)
+	nextMessage(t, messages)
+
+	insertExpression(t, ts, "1+2")
+	task := nextMessage(t, messages).GetAssignment()
+	require.NotNil(t, task)
+
+	cancel()
+
+	require.Eventually(t, func() bool {
+		dbTask, err := ts.exprRepo.GetTaskByID(int(task.Id))
+		return err == nil && dbTask.Status == models.StatusWait
+	}, time.Second, 10*time.Millisecond, "task of a closed session must be requeued")
+}
+
+func TestAgentSession_RequeueOnHeartbeatTimeout(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	ts.taskServer.HeartbeatInterval = 20 * time.Millisecond
+	ts.taskServer.HeartbeatTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, messages := openSession(t, ts, ctx, 1)
+	nextMessage(t, messages)
+
+	insertExpression(t, ts, "1+2")
+	task := nextMessage(t, messages).GetAssignment()
+	require.NotNil(t, task)
+
+	require.Eventually(t, func() bool {
+		dbTask, err := ts.exprRepo.GetTaskByID(int(task.Id))
+		return err == nil && dbTask.Status == models.StatusWait
+	}, time.Second, 10*time.Millisecond, "task of a silent agent must be requeued")
+}
+
+func TestAgentSession_CancelsWithdrawnTasks(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	ts.taskServer.HeartbeatInterval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, messages := openSession(t, ts, ctx, 1)
+	nextMessage(t, messages)
+
+	insertExpression(t, ts, "1+2")
+	task := nextMessage(t, messages).GetAssignment()
+	require.NotNil(t, task)
+
+	ts.exprRepo.UpdateTaskStatus(int(task.Id), models.StatusFailed)
+
+	cancellation := nextMessage(t, messages).GetCancellation()
+	require.NotNil(t, cancellation)
+	assert.Equal(t, task.Id, cancellation.TaskId)
+}
+
+func TestAgentSession_ConfigUpdate(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, messages := openSession(t, ts, ctx, 1)
+	nextMessage(t, messages)
+
+	ts.taskServer.UpdateAgentConfig(&proto.AgentConfig{OperationDurationMs: map[string]int64{"+": 10}})
+
+	config := nextMessage(t, messages).GetConfig()
+	require.NotNil(t, config)
+	assert.Equal(t, int64(10), config.OperationDurationMs["+"])
+}
+
+func TestAgentSession_ConfigForLateAgent(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	ts.taskServer.UpdateAgentConfig(&proto.AgentConfig{OperationDurationMs: map[string]int64{"+": 10}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, messages := openSession(t, ts, ctx, 1)
+
+	config := nextMessage(t, messages).GetConfig()
+	require.NotNil(t, config)
+	assert.Positive(t, config.HeartbeatIntervalMs)
+	assert.Equal(t, int64(10), config.OperationDurationMs["+"])
+}
@@ -0,0 +1,165 @@
+package orchestrator
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dispatchPollInterval - как часто поток заново проверяет очередь без оповещения. Оповещения TasksReady
+// и снятие паузы приходят только от своего процесса, а при нескольких репликах на одной базе
+// новые таски и смена режима очереди на другой реплике замечаются только при такой проверке
+const dispatchPollInterval = time.Second
+
+// dispatchStream - открытый агентом поток тасок. inFlight - таски, выданные в поток, результат которых еще не получен
+type dispatchStream struct {
+	capacity int
+	wake     chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[int]bool
+}
+
+func newDispatchStream(capacity int) *dispatchStream {
+	return &dispatchStream{
+		capacity: capacity,
+		wake:     make(chan struct{}, 1),
+		inFlight: make(map[int]bool),
+	}
+}
+
+func (s *dispatchStream) free() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.capacity - len(s.inFlight)
+}
+
+func (s *dispatchStream) take(taskID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight[taskID] = true
+}
+
+func (s *dispatchStream) release(taskID int) {
+	s.mu.Lock()
+	delete(s.inFlight, taskID)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StreamTasks держит поток с агентом и отправляет ему таски, как только они становятся готовыми к выполнению:
+// при появлении нового выражения или когда результат одной таски разрешает зависимости другой.
+// Одновременно агенту выдается не больше capacity тасок; слот освобождается, когда приходит результат таски.
+// Изменения, сделанные другими репликами оркестратора, поток замечает при проверке раз в dispatchPollInterval
+func (ts *TaskServer) StreamTasks(req *proto.ReceiveTasksRequest, stream grpc.ServerStreamingServer[proto.Task]) error {
+	if req.Capacity <= 0 {
+		return status.Error(codes.InvalidArgument, "capacity must be positive")
+	}
+
+	capacity := int(req.Capacity)
+	if capacity > maxBatchSize {
+		capacity = maxBatchSize
+	}
+
+	ds := newDispatchStream(capacity)
+	defer ts.forgetStream(ds)
+
+	ticker := time.NewTicker(dispatchPollInterval)
+	defer ticker.Stop()
+
+	ctx := stream.Context()
+
+	for {
+		ready := ts.ExprRepo.TasksReady()
+
+		for ds.free() > 0 {
+			task, id, err := ts.ExprRepo.GetTask(models.TaskFilter{AgentID: req.AgentId, Operations: req.Operations})
+			if errors.Is(err, models.ErrorQueuePaused) {
+				break
+			}
+			if err != nil {
+				log.Println("Failed to get task:", err)
+				return status.Errorf(codes.Internal, "failed to get task: %v", err)
+			}
+
+			if task == nil {
+				break
+			}
+
+			ts.assign(id, ds)
+
+			if err := stream.Send(toProtoTask(task)); err != nil {
+				// таска остается закрепленной за потоком: отложенный forgetStream вернет ее в очередь
+				return err
+			}
+		}
+
+		select {
+		case <-ready:
+		case <-ds.wake:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// assign запоминает, в какой поток выдана таска
+func (ts *TaskServer) assign(taskID int, ds *dispatchStream) {
+	ts.streamsMu.Lock()
+	defer ts.streamsMu.Unlock()
+
+	if ts.assigned == nil {
+		ts.assigned = make(map[int]*dispatchStream)
+	}
+	ts.assigned[taskID] = ds
+	ds.take(taskID)
+}
+
+// releaseTask освобождает слот потока, в который была выдана таска
+func (ts *TaskServer) releaseTask(taskID int) {
+	ts.streamsMu.Lock()
+	ds, ok := ts.assigned[taskID]
+	delete(ts.assigned, taskID)
+	ts.streamsMu.Unlock()
+
+	if ok {
+		ds.release(taskID)
+	}
+}
+
+// forgetStream закрывает поток и возвращает в очередь выданные в него таски, результат которых агент так и не прислал
+func (ts *TaskServer) forgetStream(ds *dispatchStream) {
+	var inFlight []int
+
+	ts.streamsMu.Lock()
+	for taskID, assigned := range ts.assigned {
+		if assigned == ds {
+			delete(ts.assigned, taskID)
+			inFlight = append(inFlight, taskID)
+		}
+	}
+	ts.streamsMu.Unlock()
+
+	for _, taskID := range inFlight {
+		requeued, err := ts.ExprRepo.RequeueTask(taskID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if requeued {
+			log.Printf("task ID-%d requeued: agent stream closed", taskID)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	"github.com/bulbosaur/calculator-with-authorization/proto"
@@ -15,15 +17,35 @@ import (
 type TaskServer struct {
 	proto.UnimplementedTaskServiceServer
 	ExprRepo models.ExpressionRepository
+
+	// Agents ведет учет агентов. Если не задан, регистрация агентов не поддерживается
+	Agents models.AgentRepository
+
+	// HeartbeatInterval и HeartbeatTimeout настраивают сессии агентов.
+	// Если не заданы, используются DefaultHeartbeatInterval и DefaultHeartbeatTimeout
+	HeartbeatInterval time.Duration
+	HeartbeatTimeout  time.Duration
+
+	streamsMu   sync.Mutex
+	assigned    map[int]*dispatchStream
+	sessions    map[*agentSession]bool
+	agentConfig *proto.AgentConfig
 }
 
 func newTaskServer(repo models.ExpressionRepository) *TaskServer {
-	return &TaskServer{ExprRepo: repo}
+	ts := &TaskServer{ExprRepo: repo}
+	if agents, ok := repo.(models.AgentRepository); ok {
+		ts.Agents = agents
+	}
+	return ts
 }
 
 // ReceiveTask обрабатывает запрос от агента на получение задачи
 func (ts *TaskServer) ReceiveTask(ctx context.Context, req *proto.GetTaskRequest) (*proto.Task, error) {
-	task, id, err := ts.ExprRepo.GetTask()
+	task, _, err := ts.ExprRepo.GetTask(models.TaskFilter{AgentID: req.AgentId, Operations: req.Operations})
+	if errors.Is(err, models.ErrorQueuePaused) {
+		return nil, status.Error(codes.Unavailable, models.ErrorQueuePaused.Error())
+	}
 	if err != nil {
 		log.Println("Failed to get task:", err)
 		return nil, status.Errorf(codes.Internal, "failed to get task: %v", err)
@@ -33,19 +55,46 @@ func (ts *TaskServer) ReceiveTask(ctx context.Context, req *proto.GetTaskRequest
 		return nil, status.Error(codes.NotFound, "no tasks available")
 	}
 
-	ts.ExprRepo.UpdateTaskStatus(id, models.StatusInProcess)
+	return toProtoTask(task), nil
+}
 
-	return &proto.Task{
-		Id:           int32(task.ID),
-		ExpressionId: int32(task.ExpressionID),
-		Arg1:         task.Arg1,
-		Arg2:         task.Arg2,
-		PrevTask_Id1: int32(task.PrevTaskID1),
-		PrevTask_Id2: int32(task.PrevTaskID2),
-		Operation:    task.Operation,
-		Status:       task.Status,
-		Result:       task.Result,
-	}, nil
+// ReceiveTasks выдает агенту сразу несколько готовых к вычислению тасок, но не больше заявленной им capacity
+// и не больше maxBatchSize. Если свободных тасок нет, возвращает пустой пакет
+func (ts *TaskServer) ReceiveTasks(ctx context.Context, req *proto.ReceiveTasksRequest) (*proto.TaskBatch, error) {
+	if req.Capacity <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "capacity must be positive")
+	}
+
+	limit := int(req.Capacity)
+	if limit > maxBatchSize {
+		limit = maxBatchSize
+	}
+
+	batch := &proto.TaskBatch{}
+	for len(batch.Tasks) < limit {
+		task, _, err := ts.ExprRepo.GetTask(models.TaskFilter{AgentID: req.AgentId, Operations: req.Operations})
+		if err != nil && len(batch.Tasks) > 0 {
+			if !errors.Is(err, models.ErrorQueuePaused) {
+				log.Println("Failed to get task:", err)
+			}
+			break
+		}
+		if errors.Is(err, models.ErrorQueuePaused) {
+			return nil, status.Error(codes.Unavailable, models.ErrorQueuePaused.Error())
+		}
+		if err != nil {
+			log.Println("Failed to get task:", err)
+			return nil, status.Errorf(codes.Internal, "failed to get task: %v", err)
+		}
+
+		if task == nil {
+			break
+		}
+
+		batch.Tasks = append(batch.Tasks, toProtoTask(task))
+	}
+
+	return batch, nil
 }
 
 // SubmitTaskResult обрабатывает результат выполнения задачи от агента
@@ -62,5 +111,62 @@ func (ts *TaskServer) SubmitTaskResult(ctx context.Context, req *proto.SubmitTas
 			req.ErrorMessage,
 		)
 	}
+	ts.releaseTask(int(req.TaskId))
+
 	return &proto.SubmitTaskResultResponse{Success: true}, nil
 }
+
+// SubmitTaskResults принимает от агента результаты нескольких тасок за один вызов.
+// Результаты, которые не удалось записать, перечисляются в FailedTaskIds, чтобы агент мог их переотправить
+func (ts *TaskServer) SubmitTaskResults(ctx context.Context, req *proto.SubmitTaskResultsRequest) (*proto.SubmitTaskResultsResponse, error) {
+	resp := &proto.SubmitTaskResultsResponse{}
+
+	for _, result := range req.Results {
+		if result.TaskId <= 0 {
+			resp.FailedTaskIds = append(resp.FailedTaskIds, result.TaskId)
+			continue
+		}
+
+		err := ts.ExprRepo.UpdateTaskResult(int(result.TaskId), result.Result, result.ErrorMessage)
+		ts.releaseTask(int(result.TaskId))
+		if err != nil {
+			log.Printf("failed to update result of task ID-%d: %v", result.TaskId, err)
+			resp.FailedTaskIds = append(resp.FailedTaskIds, result.TaskId)
+			continue
+		}
+
+		resp.Accepted++
+	}
+
+	return resp, nil
+}
+
+// maxBatchSize - максимальное количество тасок, выдаваемых агенту за один вызов ReceiveTasks
+const maxBatchSize = 100
+
+func toProtoTask(task *models.Task) *proto.Task {
+	return &proto.Task{
+		Id:           int32(task.ID),
+		ExpressionId: int32(task.ExpressionID),
+		Arg1:         task.Arg1,
+		Arg2:         task.Arg2,
+		PrevTask_Id1: int32(task.PrevTaskID1),
+		PrevTask_Id2: int32(task.PrevTaskID2),
+		Operation:    task.Operation,
+		Status:       task.Status,
+		Result:       task.Result,
+		TimeoutMs:    timeoutMs(task.Deadline),
+	}
+}
+
+// timeoutMs возвращает, сколько миллисекунд у агента осталось до дедлайна выражения. 0 - без ограничения
+func timeoutMs(deadline *time.Time) int64 {
+	if deadline == nil {
+		return 0
+	}
+	remaining := time.Until(*deadline).Milliseconds()
+	if remaining < 1 {
+		return 1
+	}
+	return remaining
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
@@ -24,38 +26,15 @@ import (
 
 type testServer struct {
 	proto.UnimplementedTaskServiceServer
-	exprRepo *repository.ExpressionModel
-	server   *grpc.Server
-	port     string
-	db       *sql.DB
+	exprRepo   *repository.ExpressionModel
+	taskServer *orchestratorGrpc.TaskServer
+	server     *grpc.Server
+	port       string
+	db         *sql.DB
 }
 
 func setupTestServer(t *testing.T) *testServer {
-	db, err := sql.Open("sqlite", ":memory:")
-	require.NoError(t, err)
-
-	_, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS expressions (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            user_id INTEGER,
-            expression TEXT NOT NULL,
-            status TEXT NOT NULL,
-            result FLOAT64 DEFAULT 0,
-            error_message TEXT DEFAULT ""
-        );
-        CREATE TABLE IF NOT EXISTS tasks (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            expressionID INTEGER NOT NULL,
-            arg1 TEXT NOT NULL,
-            arg2 TEXT NOT NULL,
-            prev_task_id1 INTEGER DEFAULT 0,
-            prev_task_id2 INTEGER DEFAULT 0,
-            operation TEXT NOT NULL,
-            status TEXT,
-            result FLOAT,
-            error_message TEXT DEFAULT ""
-        );
-    `)
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
 	require.NoError(t, err)
 
 	exprRepo := &repository.ExpressionModel{DB: db}
@@ -65,9 +44,11 @@ func setupTestServer(t *testing.T) *testServer {
 	require.NoError(t, err)
 
 	server := grpc.NewServer()
-	proto.RegisterTaskServiceServer(server, &orchestratorGrpc.TaskServer{
+	taskServer := &orchestratorGrpc.TaskServer{
 		ExprRepo: exprRepo,
-	})
+		Agents:   exprRepo,
+	}
+	proto.RegisterTaskServiceServer(server, taskServer)
 
 	go func() {
 		log.Printf("gRPC test server listening on port %s", port)
@@ -77,10 +58,11 @@ func setupTestServer(t *testing.T) *testServer {
 	}()
 
 	return &testServer{
-		exprRepo: exprRepo,
-		server:   server,
-		port:     port,
-		db:       db,
+		exprRepo:   exprRepo,
+		taskServer: taskServer,
+		server:     server,
+		port:       port,
+		db:         db,
 	}
 }
 
@@ -97,7 +79,7 @@ func TestReceiveTask(t *testing.T) {
 	exprID, err := ts.exprRepo.Insert("3+4", 1)
 	require.NoError(t, err)
 
-	err = orchestrator.Calc("3+4", exprID, ts.exprRepo)
+	_, err = orchestrator.Calc("3+4", exprID, ts.exprRepo)
 	require.NoError(t, err)
 
 	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -127,7 +109,7 @@ func TestSubmitTaskResult(t *testing.T) {
 	exprID, err := ts.exprRepo.Insert("3+4", 1)
 	require.NoError(t, err)
 
-	err = orchestrator.Calc("3+4", exprID, ts.exprRepo)
+	_, err = orchestrator.Calc("3+4", exprID, ts.exprRepo)
 	require.NoError(t, err)
 
 	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -161,7 +143,7 @@ func TestSubmitTaskError(t *testing.T) {
 
 	exprID, err := ts.exprRepo.Insert("5/0", 1)
 	require.NoError(t, err)
-	err = orchestrator.Calc("5/0", exprID, ts.exprRepo)
+	_, err = orchestrator.Calc("5/0", exprID, ts.exprRepo)
 	require.NoError(t, err)
 
 	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -203,13 +185,41 @@ func TestNoTasksAvailable(t *testing.T) {
 	assert.Equal(t, codes.NotFound, st.Code())
 }
 
+func TestReceiveTask_Paused(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	exprID, err := ts.exprRepo.Insert("3+4", 1)
+	require.NoError(t, err)
+	_, err = orchestrator.Calc("3+4", exprID, ts.exprRepo)
+	require.NoError(t, err)
+	require.NoError(t, ts.exprRepo.SetQueueState(models.QueueState{Paused: true}))
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	_, err = client.ReceiveTask(context.Background(), &proto.GetTaskRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "paused", status.Convert(err).Message())
+
+	require.NoError(t, ts.exprRepo.SetQueueState(models.QueueState{}))
+
+	resp, err := client.ReceiveTask(context.Background(), &proto.GetTaskRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(exprID), resp.ExpressionId)
+}
+
 func TestExpressionStatusUpdate(t *testing.T) {
 	ts := setupTestServer(t)
 	defer ts.teardown(t)
 
 	exprID, err := ts.exprRepo.Insert("3+4*2", 1)
 	require.NoError(t, err)
-	err = orchestrator.Calc("3+4*2", exprID, ts.exprRepo)
+	_, err = orchestrator.Calc("3+4*2", exprID, ts.exprRepo)
 	require.NoError(t, err)
 
 	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -255,7 +265,7 @@ func TestUpdateExpressionStatusOnError(t *testing.T) {
 	defer ts.teardown(t)
 
 	exprID, _ := ts.exprRepo.Insert("5/0", 1)
-	err := orchestrator.Calc("5/0", exprID, ts.exprRepo)
+	_, err := orchestrator.Calc("5/0", exprID, ts.exprRepo)
 	require.NoError(t, err)
 
 	conn, _ := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -278,7 +288,7 @@ func TestFullExpressionLifecycle(t *testing.T) {
 	defer ts.teardown(t)
 
 	exprID, _ := ts.exprRepo.Insert("3+4*2", 1)
-	err := orchestrator.Calc("3+4*2", exprID, ts.exprRepo)
+	_, err := orchestrator.Calc("3+4*2", exprID, ts.exprRepo)
 	require.NoError(t, err)
 
 	conn, _ := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -347,3 +357,262 @@ func TestSubmitTaskResult_InvalidTaskID(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, codes.InvalidArgument, st.Code())
 }
+
+func TestReceiveTask_Timeout(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	deadline := time.Now().Add(time.Minute)
+	exprID, err := ts.exprRepo.InsertExpression(&models.Expression{UserID: 1, Expression: "3+4", Deadline: &deadline})
+	require.NoError(t, err)
+	_, err = orchestrator.Calc("3+4", exprID, ts.exprRepo)
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+	resp, err := client.ReceiveTask(context.Background(), &proto.GetTaskRequest{})
+	require.NoError(t, err)
+
+	assert.Greater(t, resp.TimeoutMs, int64(0))
+	assert.LessOrEqual(t, resp.TimeoutMs, time.Minute.Milliseconds())
+}
+
+func TestReceiveTasks_Batch(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	for _, expr := range []string{"1+2", "3+4", "5+6"} {
+		exprID, err := ts.exprRepo.Insert(expr, 1)
+		require.NoError(t, err)
+		_, err = orchestrator.Calc(expr, exprID, ts.exprRepo)
+		require.NoError(t, err)
+	}
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	batch, err := client.ReceiveTasks(context.Background(), &proto.ReceiveTasksRequest{Capacity: 2})
+	require.NoError(t, err)
+	assert.Len(t, batch.Tasks, 2, "batch must be bounded by the agent capacity")
+
+	rest, err := client.ReceiveTasks(context.Background(), &proto.ReceiveTasksRequest{Capacity: 5})
+	require.NoError(t, err)
+	assert.Len(t, rest.Tasks, 1)
+
+	empty, err := client.ReceiveTasks(context.Background(), &proto.ReceiveTasksRequest{Capacity: 5})
+	require.NoError(t, err)
+	assert.Empty(t, empty.Tasks)
+
+	_, err = client.ReceiveTasks(context.Background(), &proto.ReceiveTasksRequest{})
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	var results []*proto.SubmitTaskResultRequest
+	for _, task := range append(batch.Tasks, rest.Tasks...) {
+		results = append(results, &proto.SubmitTaskResultRequest{TaskId: task.Id, Result: task.Arg1 + task.Arg2})
+	}
+	results = append(results, &proto.SubmitTaskResultRequest{TaskId: 0})
+
+	resp, err := client.SubmitTaskResults(context.Background(), &proto.SubmitTaskResultsRequest{Results: results})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), resp.Accepted)
+	assert.Equal(t, []int32{0}, resp.FailedTaskIds)
+
+	for id, want := range map[int]float64{1: 3, 2: 7, 3: 11} {
+		expr, err := ts.exprRepo.GetExpression(id)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusResolved, expr.Status)
+		assert.Equal(t, want, expr.Result)
+	}
+}
+
+func TestStreamTasks_PushesTasksWhenDependenciesResolve(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	exprID, err := ts.exprRepo.Insert("2*3+1", 1)
+	require.NoError(t, err)
+	_, err = orchestrator.Calc("2*3+1", exprID, ts.exprRepo)
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamTasks(ctx, &proto.ReceiveTasksRequest{Capacity: 1})
+	require.NoError(t, err)
+
+	received := make(chan *proto.Task, 10)
+	go func() {
+		for {
+			task, err := stream.Recv()
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- task
+		}
+	}()
+
+	var first *proto.Task
+	select {
+	case first = <-received:
+		assert.Equal(t, "*", first.Operation)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the first task")
+	}
+
+	select {
+	case task := <-received:
+		t.Fatalf("Task %d must wait until its dependency is resolved", task.Id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	start := time.Now()
+	_, err = client.SubmitTaskResult(context.Background(), &proto.SubmitTaskResultRequest{TaskId: first.Id, Result: 6})
+	require.NoError(t, err)
+
+	select {
+	case second := <-received:
+		assert.Equal(t, "+", second.Operation)
+		assert.Equal(t, first.Id, second.PrevTask_Id1)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the dependent task")
+	}
+}
+
+func TestStreamTasks_RespectsCapacity(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamTasks(ctx, &proto.ReceiveTasksRequest{Capacity: 1})
+	require.NoError(t, err)
+
+	received := make(chan *proto.Task, 10)
+	go func() {
+		for {
+			task, err := stream.Recv()
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- task
+		}
+	}()
+
+	for _, expr := range []string{"1+2", "3+4"} {
+		exprID, err := ts.exprRepo.Insert(expr, 1)
+		require.NoError(t, err)
+		_, err = orchestrator.Calc(expr, exprID, ts.exprRepo)
+		require.NoError(t, err)
+	}
+
+	var first *proto.Task
+	select {
+	case first = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for a pushed task")
+	}
+
+	select {
+	case task := <-received:
+		t.Fatalf("Task %d exceeds the agent capacity", task.Id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err = client.SubmitTaskResults(context.Background(), &proto.SubmitTaskResultsRequest{
+		Results: []*proto.SubmitTaskResultRequest{{TaskId: first.Id, Result: first.Arg1 + first.Arg2}},
+	})
+	require.NoError(t, err)
+
+	select {
+	case second := <-received:
+		assert.NotEqual(t, first.Id, second.Id)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the second task")
+	}
+}
+
+func TestStreamTasks_RequeueOnDisconnect(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	exprID, err := ts.exprRepo.Insert("1+2", 1)
+	require.NoError(t, err)
+	_, err = orchestrator.Calc("1+2", exprID, ts.exprRepo)
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.StreamTasks(ctx, &proto.ReceiveTasksRequest{AgentId: "agent-1", Capacity: 1})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+
+	cancel()
+
+	var second *proto.Task
+	require.Eventually(t, func() bool {
+		second, err = client.ReceiveTask(context.Background(), &proto.GetTaskRequest{AgentId: "agent-2"})
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "task of a closed stream must be requeued")
+	assert.Equal(t, first.Id, second.Id)
+
+	task, err := ts.exprRepo.GetTaskByID(int(second.Id))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusInProcess, task.Status)
+}
+
+func TestReceiveTasks_OnlySupportedOperations(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.teardown(t)
+
+	for _, expr := range []string{"1+2", "3*4", "5-6"} {
+		insertExpression(t, ts, expr)
+	}
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%s", ts.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := proto.NewTaskServiceClient(conn)
+
+	batch, err := client.ReceiveTasks(context.Background(), &proto.ReceiveTasksRequest{Capacity: 5, Operations: []string{"*"}})
+	require.NoError(t, err)
+	require.Len(t, batch.Tasks, 1)
+	assert.Equal(t, "*", batch.Tasks[0].Operation)
+
+	_, err = client.ReceiveTask(context.Background(), &proto.GetTaskRequest{Operations: []string{"/"}})
+	assert.Equal(t, codes.NotFound, status.Code(err), "no task must be handed out for an unsupported operation")
+
+	rest, err := client.ReceiveTasks(context.Background(), &proto.ReceiveTasksRequest{Capacity: 5})
+	require.NoError(t, err)
+	assert.Len(t, rest.Tasks, 2)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/spf13/viper"
+)
+
+// AdminAgentsHandler выводит администратору список агентов: их состояние, текущие таски и пропускную способность.
+// Агент считается offline, если не присылал heartbeat дольше agent.HEARTBEAT_TIMEOUT_MS. GET /api/v1/admin/agents
+func AdminAgentsHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offlineAfter := time.Duration(viper.GetInt("agent.HEARTBEAT_TIMEOUT_MS")) * time.Millisecond
+
+		agents, err := exprRepo.ListAgents(offlineAfter)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(agents)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/handlers"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAgentsHandler(t *testing.T) {
+	db, mock, _ := setup()
+	now := time.Now()
+	exprRepo := &repository.ExpressionModel{DB: db, Clock: func() time.Time { return now }}
+	viper.Set("agent.HEARTBEAT_TIMEOUT_MS", 5000)
+
+	mock.ExpectQuery("SELECT id, hostname, version, workers, operations, registered_at, last_seen FROM agents").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hostname", "version", "workers", "operations", "registered_at", "last_seen"}).
+			AddRow("agent-1", "host-1", "1.0", 4, "+,-", now.Add(-time.Hour).UnixMilli(), now.UnixMilli()).
+			AddRow("agent-2", "host-2", "1.0", 2, "*", now.Add(-time.Hour).UnixMilli(), now.Add(-time.Minute).UnixMilli()))
+	mock.ExpectQuery("SELECT agent_id, id FROM tasks").
+		WillReturnRows(sqlmock.NewRows([]string{"agent_id", "id"}).AddRow("agent-1", 7))
+	mock.ExpectQuery("SELECT agent_id, COUNT\\(\\*\\) FROM tasks").
+		WillReturnRows(sqlmock.NewRows([]string{"agent_id", "count"}).AddRow("agent-1", 12))
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/agents", nil)
+	w := httptest.NewRecorder()
+
+	handlers.AdminAgentsHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var agents []models.Agent
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&agents))
+	if assert.Len(t, agents, 2) {
+		assert.Equal(t, models.AgentStateBusy, agents[0].State)
+		assert.Equal(t, []int{7}, agents[0].CurrentTasks)
+		assert.Equal(t, 12, agents[0].Throughput)
+		assert.Equal(t, models.AgentStateOffline, agents[1].State)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// FlushCacheHandler сбрасывает кэш результатов выражений. DELETE /api/v1/admin/cache
+func FlushCacheHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flushed, err := exprRepo.FlushResultCache()
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("result cache flushed: %d entries removed", flushed)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.CacheFlushResult{Flushed: flushed})
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// QueueStatusHandler выводит режим очереди и сколько выражений и тасок еще не завершено. GET /api/v1/admin/queue
+func QueueStatusHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeQueueStatus(w, exprRepo)
+	}
+}
+
+// PauseQueueHandler приостанавливает выдачу тасок агентам. Начатые таски досчитываются. POST /api/v1/admin/queue/pause
+func PauseQueueHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return changeQueueState(exprRepo, func(state *models.QueueState) {
+		state.Paused = true
+	})
+}
+
+// ResumeQueueHandler возвращает очередь в обычный режим: таски снова выдаются, новые выражения принимаются.
+// POST /api/v1/admin/queue/resume
+func ResumeQueueHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return changeQueueState(exprRepo, func(state *models.QueueState) {
+		state.Paused = false
+		state.Draining = false
+	})
+}
+
+// DrainQueueHandler перестает принимать новые выражения, но дает досчитаться уже принятым. POST /api/v1/admin/queue/drain
+func DrainQueueHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return changeQueueState(exprRepo, func(state *models.QueueState) {
+		state.Draining = true
+	})
+}
+
+// changeQueueState применяет change к текущему режиму очереди и отвечает новым состоянием
+func changeQueueState(exprRepo *repository.ExpressionModel, change func(state *models.QueueState)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := exprRepo.GetQueueState()
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		change(&state)
+
+		if err := exprRepo.SetQueueState(state); err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("queue state changed: paused=%t, draining=%t", state.Paused, state.Draining)
+
+		writeQueueStatus(w, exprRepo)
+	}
+}
+
+func writeQueueStatus(w http.ResponseWriter, exprRepo *repository.ExpressionModel) {
+	status, err := exprRepo.GetQueueStatus()
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
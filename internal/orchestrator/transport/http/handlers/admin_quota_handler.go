@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// UserQuotaHandler показывает администратору лимиты пользователя и их текущее потребление
+func UserQuotaHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Invalid user ID",
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+		writeUserQuota(w, exprRepo, userID)
+	}
+}
+
+// SetUserQuotaHandler задает пользователю персональные лимиты.
+// PUT /api/v1/admin/users/{id}/quota { "max_active_expressions": , "max_tasks_per_expression": , "max_queued_tasks": }
+func SetUserQuotaHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Invalid user ID",
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+		var request models.QuotaOverride
+		defer r.Body.Close()
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: models.ErrorInvalidRequestBody.Error(),
+			})
+			return
+		}
+
+		err = exprRepo.SetUserQuota(userID, request)
+		switch {
+		case errors.Is(err, models.ErrorInvalidQuota):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: err.Error(),
+			})
+			return
+		case errors.Is(err, models.ErrorUserNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Not found",
+				ErrorMessage: err.Error(),
+			})
+			return
+		case err != nil:
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeUserQuota(w, exprRepo, userID)
+	}
+}
+
+func writeUserQuota(w http.ResponseWriter, exprRepo *repository.ExpressionModel, userID int) {
+	limits, err := exprRepo.GetUserQuota(userID, DefaultQuota())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	usage, err := exprRepo.GetQuotaUsage(userID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.UserQuota{
+		UserID: userID,
+		Limits: limits,
+		Usage:  usage,
+	})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// AdminUsersHandler выводит администратору список пользователей с их ролями и весами в планировщике
+func AdminUsersHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, err := exprRepo.ListUsers()
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+	}
+}
+
+// UserWeightHandler позволяет администратору задать вес пользователя. PUT /api/v1/admin/users/{id}/weight { "weight": }
+// Пользователь с весом 2 получает вдвое больше тасок, чем пользователь с весом 1, если оба ждут вычислений
+func UserWeightHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Invalid user ID",
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+		var request models.UserWeight
+		defer r.Body.Close()
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: models.ErrorInvalidRequestBody.Error(),
+			})
+			return
+		}
+
+		err = exprRepo.SetUserWeight(userID, request.Weight)
+		switch {
+		case errors.Is(err, models.ErrorInvalidWeight):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: err.Error(),
+			})
+			return
+		case errors.Is(err, models.ErrorUserNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Not found",
+				ErrorMessage: err.Error(),
+			})
+			return
+		case err != nil:
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{
+			"user_id": userID,
+			"weight":  request.Weight,
+		})
+	}
+}
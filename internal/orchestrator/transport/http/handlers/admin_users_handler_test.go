@@ -0,0 +1,62 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/handlers"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserWeightHandler_Success(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectExec("UPDATE users SET weight = \\? WHERE id = \\?").
+		WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest("PUT", "/api/v1/admin/users/2/weight", strings.NewReader(`{"weight":3}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	w := httptest.NewRecorder()
+
+	handlers.UserWeightHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":2,"weight":3}`, w.Body.String())
+}
+
+func TestUserWeightHandler_InvalidWeight(t *testing.T) {
+	db, _, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	req := httptest.NewRequest("PUT", "/api/v1/admin/users/2/weight", strings.NewReader(`{"weight":0}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	w := httptest.NewRecorder()
+
+	handlers.UserWeightHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserWeightHandler_UserNotFound(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectExec("UPDATE users SET weight = \\? WHERE id = \\?").
+		WithArgs(3, 42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := httptest.NewRequest("PUT", "/api/v1/admin/users/42/weight", strings.NewReader(`{"weight":3}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	handlers.UserWeightHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
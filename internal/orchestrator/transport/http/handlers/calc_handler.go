@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
 	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/spf13/viper"
 )
 
 // RegHandler принимает введенное пользователем выражение и занимается его дальнейшей обработкой. После всех валидаций и подсчетов возвращает результат и код ответа
@@ -32,7 +35,133 @@ func RegHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
 			return
 		}
 
-		id, err := exprRepo.Insert(request.Expression, userID)
+		if !priorityAllowed(exprRepo, userID, request.Priority) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: models.ErrorPriorityOutOfRange.Error(),
+			})
+			return
+		}
+
+		now := time.Now()
+		if request.Deadline != nil && !request.Deadline.After(now) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: models.ErrorInvalidDeadline.Error(),
+			})
+			return
+		}
+
+		if request.CallbackURL != "" {
+			if err := orchestrator.ValidateCallbackURL(request.CallbackURL); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(models.ErrorResponse{
+					Error:        "Bad request",
+					ErrorMessage: err.Error(),
+				})
+				return
+			}
+		}
+
+		queueState, err := exprRepo.GetQueueState()
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "something went wrong",
+				ErrorMessage: "failed to check queue state",
+			})
+			return
+		}
+		if queueState.Draining {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Service unavailable",
+				ErrorMessage: models.ErrorQueueDraining.Error(),
+			})
+			return
+		}
+
+		cacheKey := ""
+		if request.UseCache && viper.GetBool("cache.ENABLED") {
+			if key, err := orchestrator.CacheKey(request.Expression, viper.GetString("cache.PRECISION_MODE")); err == nil {
+				cacheKey = key
+			}
+		}
+
+		if cacheKey != "" {
+			result, ok, err := exprRepo.GetCachedResult(cacheKey)
+			if err != nil {
+				log.Printf("failed to check result cache: %v", err)
+			} else if ok {
+				id, err := exprRepo.InsertCachedExpression(&models.Expression{
+					UserID:      userID,
+					Expression:  request.Expression,
+					Priority:    request.Priority,
+					CallbackURL: request.CallbackURL,
+				}, cacheKey, result)
+				if err != nil {
+					log.Printf("something went wrong while creating a record in the database. %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(w).Encode(models.ErrorResponse{
+						Error:        "something went wrong",
+						ErrorMessage: "an error occurred while writing to the database",
+					})
+					return
+				}
+
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(models.RegisteredExpression{
+					ID:    id,
+					Cache: models.CacheHit,
+				})
+				return
+			}
+		}
+
+		wait, overloaded, err := orchestrator.CheckBackpressure(exprRepo, DefaultBackpressure(), now)
+		if err != nil {
+			log.Printf("failed to check queue load: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "something went wrong",
+				ErrorMessage: "failed to check queue load",
+			})
+			return
+		}
+		if overloaded {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Too many requests",
+				ErrorMessage: models.ErrorQueueOverloaded.Error(),
+			})
+			return
+		}
+
+		expr := &models.Expression{
+			UserID:      userID,
+			Expression:  request.Expression,
+			Priority:    request.Priority,
+			CallbackURL: request.CallbackURL,
+			Deadline: orchestrator.ResolveDeadline(
+				request.Deadline,
+				now,
+				time.Duration(viper.GetInt("expression.DEFAULT_TIMEOUT_S"))*time.Second,
+			),
+		}
+
+		var (
+			id       int
+			quotaErr *models.QuotaError
+		)
+		if taskCount, countErr := orchestrator.CountTasks(request.Expression); countErr == nil {
+			id, quotaErr, err = orchestrator.InsertWithinQuota(exprRepo, expr, taskCount, DefaultQuota())
+		} else {
+			id, err = exprRepo.InsertExpression(expr)
+		}
 		if err != nil {
 			log.Printf("something went wrong while creating a record in the database. %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -42,8 +171,19 @@ func RegHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
 			})
 			return
 		}
+		if quotaErr != nil {
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(quotaErr)
+			return
+		}
 
-		err = orchestrator.Calc(request.Expression, id, exprRepo)
+		if cacheKey != "" {
+			if err := exprRepo.SetExpressionCacheKey(id, cacheKey); err != nil {
+				log.Println(err)
+			}
+		}
+
+		stats, err := orchestrator.Calc(request.Expression, id, exprRepo)
 		if err != nil {
 			exprRepo.UpdateStatus(id, models.StatusFailed)
 			w.WriteHeader(http.StatusUnprocessableEntity)
@@ -54,11 +194,84 @@ func RegHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
 			return
 		}
 
+		if err := exprRepo.ReleaseReservedTasks(id); err != nil {
+			log.Println(err)
+		}
+
 		response := models.RegisteredExpression{
-			ID: id,
+			ID:                  id,
+			Tasks:               stats.Tasks,
+			TasksSaved:          stats.TasksSaved,
+			EstimatedCompletion: estimateCompletion(exprRepo, id),
+		}
+		if cacheKey != "" {
+			response.Cache = models.CacheMiss
 		}
 
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// priorityAllowed проверяет, что приоритет не выходит за пределы, разрешенные роли пользователя:
+// обычным пользователям доступен диапазон от 0 до priority.USER_MAX, администраторам - до priority.ADMIN_MAX
+func priorityAllowed(exprRepo *repository.ExpressionModel, userID, priority int) bool {
+	if priority < 0 {
+		return false
+	}
+	if priority <= viper.GetInt("priority.USER_MAX") {
+		return true
+	}
+	if priority > viper.GetInt("priority.ADMIN_MAX") {
+		return false
+	}
+
+	user, err := exprRepo.GetUserByID(userID)
+	if err != nil {
+		log.Printf("failed to get user ID-%d: %v", userID, err)
+		return false
+	}
+	return user.Role == models.RoleAdmin
+}
+
+// DefaultQuota возвращает лимиты пользователей по умолчанию из конфигурации
+func DefaultQuota() models.Quota {
+	return models.Quota{
+		MaxActiveExpressions:  viper.GetInt("quota.MAX_ACTIVE_EXPRESSIONS"),
+		MaxTasksPerExpression: viper.GetInt("quota.MAX_TASKS_PER_EXPRESSION"),
+		MaxQueuedTasks:        viper.GetInt("quota.MAX_QUEUED_TASKS"),
+	}
+}
+
+// DefaultBackpressure возвращает порог общей очереди тасок из конфигурации
+func DefaultBackpressure() orchestrator.Backpressure {
+	return orchestrator.Backpressure{
+		MaxQueuedTasks: viper.GetInt("backpressure.MAX_QUEUED_TASKS"),
+		MaxRetryAfter:  time.Duration(viper.GetInt("backpressure.MAX_RETRY_AFTER_S")) * time.Second,
+	}
+}
+
+// estimateCompletion оценивает время завершения выражения по его оставшимся таскам, очереди перед ним
+// и числу живых воркеров. Ошибки только логируются: без оценки ответ остается корректным
+func estimateCompletion(exprRepo *repository.ExpressionModel, exprID int) *time.Time {
+	tasks, err := exprRepo.ListExpressionTasks(exprID)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	ahead, err := exprRepo.QueuedWorkAhead(exprID)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	offlineAfter := time.Duration(viper.GetInt("agent.HEARTBEAT_TIMEOUT_MS")) * time.Millisecond
+	workers, err := exprRepo.LiveWorkers(offlineAfter)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	return orchestrator.EstimateCompletion(tasks, ahead, orchestrator.OperationDurations(), workers, time.Now())
+}
@@ -2,6 +2,7 @@ package handlers_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -12,9 +13,21 @@ import (
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/handlers"
 	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
+func expectDefaultQuota(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectQuery("SELECT max_active_expressions, max_tasks_per_expression, max_queued_tasks FROM user_quotas WHERE user_id = \\?").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"max_active_expressions", "max_tasks_per_expression", "max_queued_tasks"}))
+}
+
+func expectQueueState(mock sqlmock.Sqlmock, draining bool) {
+	mock.ExpectQuery("SELECT paused, draining FROM queue_state").
+		WillReturnRows(sqlmock.NewRows([]string{"paused", "draining"}).AddRow(false, draining))
+}
+
 func TestRegHandler_InvalidRequestBody(t *testing.T) {
 	db, _, _ := setup()
 	exprRepo := &repository.ExpressionModel{DB: db}
@@ -30,8 +43,10 @@ func TestRegHandler_DBError(t *testing.T) {
 	db, mock, _ := setup()
 	exprRepo := &repository.ExpressionModel{DB: db}
 
+	expectQueueState(mock, false)
+	expectDefaultQuota(mock, 1)
 	mock.ExpectExec("INSERT INTO expressions").
-		WithArgs(sqlmock.AnyArg(), "2+2", models.StatusWait, 0.0).
+		WithArgs(sqlmock.AnyArg(), "2+2", models.StatusWait, 0, 0, sqlmock.AnyArg()).
 		WillReturnError(errors.New("DB error"))
 
 	handler := handlers.RegHandler(exprRepo)
@@ -51,3 +66,243 @@ func TestRegHandler_DBError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "something went wrong")
 }
+
+func TestRegHandler_PriorityOutOfRange(t *testing.T) {
+	viper.Set("priority.USER_MAX", 5)
+	viper.Set("priority.ADMIN_MAX", 10)
+	defer viper.Reset()
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectQuery("SELECT id, login, password_hash, role, weight FROM users WHERE id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "weight"}).
+			AddRow(1, "user", "hash", models.RoleUser, 1))
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2","priority":8}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrorPriorityOutOfRange.Error())
+}
+
+func TestRegHandler_AdminPriority(t *testing.T) {
+	viper.Set("priority.USER_MAX", 5)
+	viper.Set("priority.ADMIN_MAX", 10)
+	defer viper.Reset()
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectQuery("SELECT id, login, password_hash, role, weight FROM users WHERE id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "weight"}).
+			AddRow(1, "admin", "hash", models.RoleAdmin, 1))
+	expectQueueState(mock, false)
+	expectDefaultQuota(mock, 1)
+	mock.ExpectExec("INSERT INTO expressions").
+		WithArgs(1, "2+2", models.StatusWait, 0, 8, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+		WillReturnError(errors.New("DB error"))
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2","priority":8}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegHandler_QuotaExceeded(t *testing.T) {
+	viper.Set("quota.MAX_ACTIVE_EXPRESSIONS", 2)
+	defer viper.Reset()
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectQueueState(mock, false)
+	expectDefaultQuota(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET id = id WHERE id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), COALESCE\\(SUM\\(CASE WHEN reserved_tasks > inserted_tasks(.|\\n)+WHERE e.user_id = \\?").
+		WithArgs(1, models.StatusWait, models.StatusInProcess).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "reserved"}).AddRow(2, 0))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks").
+		WithArgs(1, models.StatusWait, models.StatusInProcess).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectRollback()
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	var response models.QuotaError
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "max_active_expressions", response.Quota)
+	assert.Equal(t, 2, response.Limit)
+	assert.Equal(t, 2, response.Usage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegHandler_TooManyTasksPerExpression(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectQueueState(mock, false)
+	mock.ExpectQuery("SELECT max_active_expressions, max_tasks_per_expression, max_queued_tasks FROM user_quotas WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"max_active_expressions", "max_tasks_per_expression", "max_queued_tasks"}).
+			AddRow(nil, 2, nil))
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"1+2+3+4"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	var response models.QuotaError
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "max_tasks_per_expression", response.Quota)
+	assert.Equal(t, 3, response.Requested)
+}
+
+func TestRegHandler_DeadlineInPast(t *testing.T) {
+	db, _, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2","deadline":"2020-01-01T00:00:00Z"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrorInvalidDeadline.Error())
+}
+
+func TestRegHandler_CacheHit(t *testing.T) {
+	viper.Set("cache.ENABLED", true)
+	viper.Set("cache.PRECISION_MODE", "float64")
+	defer viper.Reset()
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectQueueState(mock, false)
+	mock.ExpectQuery("SELECT result FROM result_cache WHERE cache_key = \\? AND expires_at > \\?").
+		WithArgs("float64:((2+2)*3)", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"result"}).AddRow(12.0))
+	mock.ExpectExec("INSERT INTO expressions").
+		WithArgs(1, "(2 + 02) * 3", models.StatusResolved, 12.0, 0, "float64:((2+2)*3)", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"(2 + 02) * 3","use_cache":true}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.RegisteredExpression
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, 7, response.ID)
+	assert.Equal(t, models.CacheHit, response.Cache)
+	assert.Equal(t, 0, response.Tasks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegHandler_CacheDisabled(t *testing.T) {
+	viper.Set("cache.ENABLED", false)
+	defer viper.Reset()
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectQueueState(mock, false)
+	expectDefaultQuota(mock, 1)
+	mock.ExpectExec("INSERT INTO expressions").
+		WithArgs(1, "2+2", models.StatusWait, 0, 0, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+		WillReturnError(errors.New("DB error"))
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2","use_cache":true}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet(), "result cache must not be queried when it is disabled")
+}
+
+func TestRegHandler_Draining(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectQueueState(mock, true)
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrorQueueDraining.Error())
+	assert.NoError(t, mock.ExpectationsWereMet(), "draining orchestrator must not write new expressions")
+}
+
+func TestRegHandler_Backpressure(t *testing.T) {
+	viper.Set("backpressure.MAX_QUEUED_TASKS", 10)
+	viper.Set("backpressure.MAX_RETRY_AFTER_S", 60)
+	defer viper.Reset()
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectQueueState(mock, false)
+	mock.ExpectQuery("SELECT COALESCE").
+		WillReturnRows(sqlmock.NewRows([]string{"queued", "finished"}).AddRow(15, 30))
+
+	handler := handlers.RegHandler(exprRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"), "5 extra tasks at 30 tasks per minute take 10 seconds")
+	assert.Contains(t, w.Body.String(), models.ErrorQueueOverloaded.Error())
+	assert.NoError(t, mock.ExpectationsWereMet(), "overloaded orchestrator must not write new expressions")
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// EventsHandler выводит историю смены статусов тасок выражения. GET /api/v1/expressions/{id}/events
+func EventsHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expr, ok := userExpression(w, r, exprRepo)
+		if !ok {
+			return
+		}
+
+		events, err := exprRepo.ListTaskEvents(expr.ID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+	}
+}
+
+// userExpression достает выражение {id} из запроса и проверяет, что оно принадлежит пользователю.
+// Если нет, пишет ответ с ошибкой и возвращает false
+func userExpression(w http.ResponseWriter, r *http.Request, exprRepo *repository.ExpressionModel) (*models.Expression, bool) {
+	userID, ok := r.Context().Value(models.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	exprID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ErrorResponse{
+			Error:        "Invalid expression ID",
+			ErrorMessage: err.Error(),
+		})
+		return nil, false
+	}
+
+	expr, err := exprRepo.GetExpression(exprID)
+	switch {
+	case errors.Is(err, models.ErrorExpressionNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.ErrorResponse{
+			Error:        "Not found",
+			ErrorMessage: err.Error(),
+		})
+		return nil, false
+	case err != nil:
+		log.Println(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if expr.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return nil, false
+	}
+
+	return expr, true
+}
@@ -0,0 +1,86 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/handlers"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func eventsRequest(exprID string, userID int) *http.Request {
+	req := httptest.NewRequest("GET", "/api/v1/expressions/"+exprID+"/events", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": exprID})
+	return req.WithContext(context.WithValue(req.Context(), models.UserIDKey, userID))
+}
+
+func expectExpression(mock sqlmock.Sqlmock, id, userID int) {
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE id = \\?").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message", "priority", "deadline", "created_at", "started_at", "finished_at"}).
+			AddRow(id, userID, "2+2", models.StatusResolved, 4, "", 0, nil, nil, nil, nil))
+}
+
+func TestEventsHandler_Success(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+	now := time.Now()
+
+	expectExpression(mock, 1, 7)
+	mock.ExpectQuery("SELECT id, task_id, expression_id, status, agent_id, error_message, created_at FROM task_events").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_id", "expression_id", "status", "agent_id", "error_message", "created_at"}).
+			AddRow(1, 1, 1, models.StatusWait, nil, "", now.UnixMilli()).
+			AddRow(2, 1, 1, models.StatusInProcess, "agent-1", "", now.Add(time.Second).UnixMilli()).
+			AddRow(3, 1, 1, models.StatusResolved, "agent-1", "", now.Add(2*time.Second).UnixMilli()))
+
+	w := httptest.NewRecorder()
+	handlers.EventsHandler(exprRepo)(w, eventsRequest("1", 7))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var events []models.TaskEvent
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, models.StatusWait, events[0].Status)
+		assert.Empty(t, events[0].AgentID)
+		assert.Equal(t, "agent-1", events[1].AgentID)
+		assert.Equal(t, models.StatusResolved, events[2].Status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventsHandler_ForeignExpression(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectExpression(mock, 1, 7)
+
+	w := httptest.NewRecorder()
+	handlers.EventsHandler(exprRepo)(w, eventsRequest("1", 8))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestEventsHandler_NotFound(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE id = \\?").
+		WithArgs(42).
+		WillReturnError(sql.ErrNoRows)
+
+	w := httptest.NewRecorder()
+	handlers.EventsHandler(exprRepo)(w, eventsRequest("42", 7))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
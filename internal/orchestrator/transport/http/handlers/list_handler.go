@@ -2,17 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/auth"
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
 )
 
-// ListHandler выводит список всех выражений
+// ListHandler выводит список выражений пользователя. Выборку можно ограничить параметрами
+// created_after, created_before, started_after, started_before, finished_after и finished_before в формате RFC 3339,
+// а отсортировать параметром sort=created_at|started_at|finished_at (с минусом в начале - по убыванию)
 func ListHandler(authProvider auth.Provider, exprRepo *repository.ExpressionModel) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -37,31 +41,61 @@ func ListHandler(authProvider auth.Provider, exprRepo *repository.ExpressionMode
 
 		userID := claims.UserID
 
-		rows, err := exprRepo.DB.Query("SELECT * FROM expressions WHERE user_id = $1", userID)
+		filter, err := parseExpressionFilter(r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+		expressions, err := exprRepo.ListExpressions(userID, filter)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
-
-		var expressions []models.Expression
-		var result string
-
-		for rows.Next() {
-			var expr models.Expression
-			err := rows.Scan(&expr.ID, &expr.UserID, &expr.Expression, &expr.Status, &result, &expr.ErrorMessage)
-			if err != nil {
-				log.Println(err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-				return
-			}
-
-			expr.Result, _ = strconv.ParseFloat(result, 64)
-			expressions = append(expressions, expr)
-		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(expressions)
 	}
 }
+
+// parseExpressionFilter разбирает параметры выборки и сортировки списка выражений
+func parseExpressionFilter(query url.Values) (models.ExpressionFilter, error) {
+	var filter models.ExpressionFilter
+
+	for param, dst := range map[string]**time.Time{
+		"created_after":   &filter.CreatedAfter,
+		"created_before":  &filter.CreatedBefore,
+		"started_after":   &filter.StartedAfter,
+		"started_before":  &filter.StartedBefore,
+		"finished_after":  &filter.FinishedAfter,
+		"finished_before": &filter.FinishedBefore,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %v", param, err)
+		}
+		*dst = &t
+	}
+
+	sort := query.Get("sort")
+	filter.Descending = strings.HasPrefix(sort, "-")
+	filter.SortBy = strings.TrimPrefix(sort, "-")
+
+	switch filter.SortBy {
+	case "", "created_at", "started_at", "finished_at":
+	default:
+		return filter, fmt.Errorf("%w: %s", models.ErrorInvalidSortKey, filter.SortBy)
+	}
+
+	return filter, nil
+}
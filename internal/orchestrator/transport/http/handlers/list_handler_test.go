@@ -94,7 +94,7 @@ func TestListHandler_DBError(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer validtoken")
 	w := httptest.NewRecorder()
 
-	mock.ExpectQuery("SELECT \\* FROM expressions WHERE user_id = \\$1").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE user_id = \\?").
 		WithArgs(1).
 		WillReturnError(errors.New("db error"))
 
@@ -120,11 +120,11 @@ func TestListHandler_Success(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer validtoken")
 	w := httptest.NewRecorder()
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message"}).
-		AddRow(1, 1, "2+2", "completed", "4", "").
-		AddRow(2, 1, "5/0", "failed", "", "division by zero")
+	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message", "priority", "deadline", "created_at", "started_at", "finished_at"}).
+		AddRow(1, 1, "2+2", "completed", "4", "", 0, nil, nil, nil, nil).
+		AddRow(2, 1, "5/0", "failed", "", "division by zero", 0, nil, nil, nil, nil)
 
-	mock.ExpectQuery("SELECT \\* FROM expressions WHERE user_id = \\$1").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE user_id = \\?").
 		WithArgs(1).
 		WillReturnRows(rows)
 
@@ -171,9 +171,9 @@ func TestListHandler_EmptyResult(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+signedToken)
 	w := httptest.NewRecorder()
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message"})
+	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message", "priority", "deadline", "created_at", "started_at", "finished_at"})
 
-	mock.ExpectQuery("SELECT \\* FROM expressions WHERE user_id = \\$1").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE user_id = \\?").
 		WithArgs(1).
 		WillReturnRows(rows)
 
@@ -190,3 +190,66 @@ func TestListHandler_EmptyResult(t *testing.T) {
 		t.Errorf("Expected empty array response; got %s", body)
 	}
 }
+
+func TestListHandler_FilterAndSort(t *testing.T) {
+	mockAuth := &mock.AuthProvider{
+		ParseJWTFunc: func(tokenString string) (*auth.Claims, error) {
+			return &auth.Claims{UserID: 1}, nil
+		},
+	}
+
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+	handler := handlers.ListHandler(mockAuth, exprRepo)
+
+	after := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT .* FROM expressions WHERE user_id = \\? AND created_at >= \\? ORDER BY finished_at IS NULL, finished_at DESC, id").
+		WithArgs(1, after.UnixMilli()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message", "priority", "deadline", "created_at", "started_at", "finished_at"}).
+			AddRow(1, 1, "2+2", "done", "4", "", 0, nil, after.UnixMilli(), after.UnixMilli()+100, after.UnixMilli()+300))
+
+	req, _ := http.NewRequest("GET", "/expressions?created_after=2026-10-19T12:00:00Z&sort=-finished_at", nil)
+	req.Header.Set("Authorization", "Bearer validtoken")
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d; got %d", http.StatusOK, w.Code)
+	}
+
+	var response []models.Expression
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if len(response) != 1 || response[0].FinishedAt == nil || response[0].FinishedAt.Sub(*response[0].CreatedAt) != 300*time.Millisecond {
+		t.Errorf("Unexpected timings in response: %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListHandler_InvalidFilter(t *testing.T) {
+	mockAuth := &mock.AuthProvider{
+		ParseJWTFunc: func(tokenString string) (*auth.Claims, error) {
+			return &auth.Claims{UserID: 1}, nil
+		},
+	}
+
+	db, _, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+	handler := handlers.ListHandler(mockAuth, exprRepo)
+
+	for _, query := range []string{"sort=priority", "created_after=yesterday"} {
+		req, _ := http.NewRequest("GET", "/expressions?"+query, nil)
+		req.Header.Set("Authorization", "Bearer validtoken")
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d; got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -64,6 +64,11 @@ func ResultHandler(provider auth.Provider, exprRepo *repository.ExpressionModel)
 			return
 		}
 
+		switch expr.Status {
+		case models.StatusNew, models.StatusWait, models.StatusInProcess:
+			expr.EstimatedCompletion = estimateCompletion(exprRepo, expr.ID)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(models.Response{
 			Expression: *expr,
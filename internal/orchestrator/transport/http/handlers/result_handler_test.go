@@ -99,7 +99,7 @@ func TestResultHandler_RepoError(t *testing.T) {
 	db, mockDB, _ := setup()
 	exprRepo := &repository.ExpressionModel{DB: db}
 
-	mockDB.ExpectQuery("SELECT id, user_id, expression, status, result, error_message FROM expressions WHERE id = \\?").
+	mockDB.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE id = \\?").
 		WithArgs(1).
 		WillReturnError(sql.ErrConnDone)
 
@@ -144,10 +144,10 @@ func TestResultHandler_Forbidden(t *testing.T) {
 		ErrorMessage: "",
 	}
 
-	mockDB.ExpectQuery("SELECT id, user_id, expression, status, result, error_message FROM expressions WHERE id = \\?").
+	mockDB.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE id = \\?").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message"}).
-			AddRow(expression.ID, expression.UserID, expression.Expression, expression.Status, expression.Result, expression.ErrorMessage))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message", "priority", "deadline", "created_at", "started_at", "finished_at"}).
+			AddRow(expression.ID, expression.UserID, expression.Expression, expression.Status, expression.Result, expression.ErrorMessage, expression.Priority, nil, nil, nil, nil))
 
 	handler := handlers.ResultHandler(mockService, exprRepo)
 
@@ -181,10 +181,10 @@ func TestResultHandler_Success(t *testing.T) {
 		ErrorMessage: "",
 	}
 
-	mockDB.ExpectQuery("SELECT id, user_id, expression, status, result, error_message FROM expressions WHERE id = \\?").
+	mockDB.ExpectQuery("SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at FROM expressions WHERE id = \\?").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message"}).
-			AddRow(expression.ID, expression.UserID, expression.Expression, expression.Status, expression.Result, expression.ErrorMessage))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "error_message", "priority", "deadline", "created_at", "started_at", "finished_at"}).
+			AddRow(expression.ID, expression.UserID, expression.Expression, expression.Status, expression.Result, expression.ErrorMessage, expression.Priority, nil, nil, nil, nil))
 
 	handler := handlers.ResultHandler(mockService, exprRepo)
 
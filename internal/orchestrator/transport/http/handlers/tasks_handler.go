@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+const (
+	graphFormatJSON    = "json"
+	graphFormatDOT     = "dot"
+	graphFormatMermaid = "mermaid"
+
+	contentTypeDOT     = "text/vnd.graphviz"
+	contentTypeMermaid = "text/vnd.mermaid"
+)
+
+// TasksHandler выводит все таски выражения вместе со связями между ними, чтобы было видно,
+// какая часть выражения еще не посчитана. GET /api/v1/expressions/{id}/tasks
+//
+// Параметр format=dot|mermaid (или заголовок Accept: text/vnd.graphviz | text/vnd.mermaid)
+// отдает граф тасок как диаграмму Graphviz или Mermaid, раскрашенную по статусам тасок
+func TasksHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := taskGraphFormat(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+		expr, ok := userExpression(w, r, exprRepo)
+		if !ok {
+			return
+		}
+
+		tasks, err := exprRepo.ListExpressionTasks(expr.ID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		switch format {
+		case graphFormatDOT:
+			w.Header().Set("Content-Type", contentTypeDOT+"; charset=utf-8")
+			w.Write([]byte(orchestrator.TaskGraphDOT(tasks)))
+		case graphFormatMermaid:
+			w.Header().Set("Content-Type", contentTypeMermaid+"; charset=utf-8")
+			w.Write([]byte(orchestrator.TaskGraphMermaid(tasks)))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(tasks)
+		}
+	}
+}
+
+// taskGraphFormat выбирает формат ответа: параметр format важнее заголовка Accept
+func taskGraphFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case graphFormatJSON, graphFormatDOT, graphFormatMermaid:
+			return format, nil
+		}
+		return "", fmt.Errorf("unsupported format %q, expected json, dot or mermaid", format)
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, contentTypeDOT):
+		return graphFormatDOT, nil
+	case strings.Contains(accept, contentTypeMermaid):
+		return graphFormatMermaid, nil
+	}
+
+	return graphFormatJSON, nil
+}
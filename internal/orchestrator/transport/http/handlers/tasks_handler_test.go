@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/handlers"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func tasksRequest(exprID string, userID int) *http.Request {
+	req := httptest.NewRequest("GET", "/api/v1/expressions/"+exprID+"/tasks", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": exprID})
+	return req.WithContext(context.WithValue(req.Context(), models.UserIDKey, userID))
+}
+
+func TestTasksHandler_Success(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectExpression(mock, 1, 7)
+	mock.ExpectQuery("SELECT t.id, t.expressionID").
+		WithArgs(models.StatusResolved, models.StatusResolved, 1).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "expressionID", "arg1", "arg2", "prev_task_id1", "prev_task_id2", "operation", "status",
+			"result", "error_message", "agent_id", "priority", "created_at", "started_at", "finished_at",
+		}).
+			AddRow(1, 1, 2, 3, 0, 0, "*", models.StatusResolved, 6, "", "agent-1", 0, nil, nil, nil).
+			AddRow(2, 1, 6, 0, 1, 0, "+", models.StatusWait, 0, "", nil, 0, nil, nil, nil))
+
+	w := httptest.NewRecorder()
+	handlers.TasksHandler(exprRepo)(w, tasksRequest("1", 7))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tasks []models.Task
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&tasks))
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "agent-1", tasks[0].AgentID)
+		assert.Equal(t, 6.0, tasks[0].Result)
+		assert.Equal(t, 1, tasks[1].PrevTaskID1)
+		assert.Equal(t, 6.0, tasks[1].Arg1)
+		assert.Equal(t, models.StatusWait, tasks[1].Status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTasksHandler_ForeignExpression(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectExpression(mock, 1, 7)
+
+	w := httptest.NewRecorder()
+	handlers.TasksHandler(exprRepo)(w, tasksRequest("1", 8))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestTasksHandler_DOTFormat(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectExpression(mock, 1, 7)
+	mock.ExpectQuery("SELECT t.id, t.expressionID").
+		WithArgs(models.StatusResolved, models.StatusResolved, 1).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "expressionID", "arg1", "arg2", "prev_task_id1", "prev_task_id2", "operation", "status",
+			"result", "error_message", "agent_id", "priority", "created_at", "started_at", "finished_at",
+		}).
+			AddRow(1, 1, 2, 3, 0, 0, "*", models.StatusResolved, 6, "", "agent-1", 0, nil, nil, nil).
+			AddRow(2, 1, 6, 4, 1, 0, "+", models.StatusWait, 0, "", nil, 0, nil, nil, nil))
+
+	req := tasksRequest("1", 7)
+	req.Header.Set("Accept", "text/vnd.graphviz")
+
+	w := httptest.NewRecorder()
+	handlers.TasksHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/vnd.graphviz"))
+	assert.Contains(t, w.Body.String(), "t1 -> t2;")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTasksHandler_UnsupportedFormat(t *testing.T) {
+	db, _, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	req := tasksRequest("1", 7)
+	req.URL.RawQuery = "format=png"
+
+	w := httptest.NewRecorder()
+	handlers.TasksHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
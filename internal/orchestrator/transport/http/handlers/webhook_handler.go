@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// WebhookHandler выводит адрес, на который по умолчанию приходят уведомления о выражениях пользователя.
+// GET /api/v1/webhook
+func WebhookHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(models.UserIDKey).(int)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		writeWebhook(w, exprRepo, userID)
+	}
+}
+
+// SetWebhookHandler задает адрес уведомлений пользователя по умолчанию, пустой url их отключает.
+// PUT /api/v1/webhook { "url": }
+func SetWebhookHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(models.UserIDKey).(int)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		var request models.Webhook
+		defer r.Body.Close()
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Bad request",
+				ErrorMessage: models.ErrorInvalidRequestBody.Error(),
+			})
+			return
+		}
+
+		if request.URL != "" {
+			if err := orchestrator.ValidateCallbackURL(request.URL); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(models.ErrorResponse{
+					Error:        "Bad request",
+					ErrorMessage: err.Error(),
+				})
+				return
+			}
+		}
+
+		err := exprRepo.SetUserWebhook(userID, request.URL)
+		switch {
+		case errors.Is(err, models.ErrorUserNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Error:        "Not found",
+				ErrorMessage: err.Error(),
+			})
+			return
+		case err != nil:
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeWebhook(w, exprRepo, userID)
+	}
+}
+
+// DeliveriesHandler выводит уведомления о завершении выражения и результаты попыток их доставки.
+// GET /api/v1/expressions/{id}/deliveries
+func DeliveriesHandler(exprRepo *repository.ExpressionModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expr, ok := userExpression(w, r, exprRepo)
+		if !ok {
+			return
+		}
+
+		deliveries, err := exprRepo.ListWebhookDeliveries(expr.ID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+func writeWebhook(w http.ResponseWriter, exprRepo *repository.ExpressionModel, userID int) {
+	url, err := exprRepo.GetUserWebhook(userID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.Webhook{URL: url})
+}
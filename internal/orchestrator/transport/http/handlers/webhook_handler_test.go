@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/handlers"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWebhookHandler(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectExec("UPDATE users SET webhook_url").
+		WithArgs("https://203.0.113.10/hook", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT webhook_url FROM users").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"webhook_url"}).AddRow("https://203.0.113.10/hook"))
+
+	req := httptest.NewRequest("PUT", "/api/v1/webhook", strings.NewReader(`{"url":"https://203.0.113.10/hook"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 7))
+	w := httptest.NewRecorder()
+
+	handlers.SetWebhookHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"url":"https://203.0.113.10/hook"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetWebhookHandler_InvalidURL(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	req := httptest.NewRequest("PUT", "/api/v1/webhook", strings.NewReader(`{"url":"ftp://example.com"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 7))
+	w := httptest.NewRecorder()
+
+	handlers.SetWebhookHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrorInvalidCallbackURL.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliveriesHandler(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	expectExpression(mock, 1, 7)
+	mock.ExpectQuery("SELECT id, expression_id, url, payload").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "expression_id", "url", "payload", "status", "attempts", "response_code", "last_error",
+			"created_at", "next_attempt_at", "delivered_at",
+		}).AddRow(3, 1, "https://203.0.113.10/hook", `{"expression_id":1,"status":"done"}`, models.DeliveryPending, 1, 500,
+			"unexpected response status 500 Internal Server Error", 1000, 2000, nil))
+
+	req := httptest.NewRequest("GET", "/api/v1/expressions/1/deliveries", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 7))
+	w := httptest.NewRecorder()
+
+	handlers.DeliveriesHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var deliveries []models.WebhookDelivery
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+		assert.Equal(t, 500, deliveries[0].ResponseCode)
+		assert.JSONEq(t, `{"expression_id":1,"status":"done"}`, string(deliveries[0].Payload))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegHandler_InvalidCallbackURL(t *testing.T) {
+	db, mock, _ := setup()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", strings.NewReader(`{"expression":"2+2","callback_url":"not a url"}`))
+	req = req.WithContext(context.WithValue(req.Context(), models.UserIDKey, 1))
+	w := httptest.NewRecorder()
+
+	handlers.RegHandler(exprRepo)(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrorInvalidCallbackURL.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
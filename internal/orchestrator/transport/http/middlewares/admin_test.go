@@ -0,0 +1,72 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/transport/http/middlewares"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRequest(userID int) *http.Request {
+	req, _ := http.NewRequest("GET", "/api/v1/admin/users", nil)
+	return req.WithContext(context.WithValue(req.Context(), models.UserIDKey, userID))
+}
+
+func TestAdminMiddleware_Admin(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectQuery("SELECT id, login, password_hash, role, weight FROM users WHERE id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "weight"}).
+			AddRow(1, "admin", "hash", models.RoleAdmin, 1))
+
+	handler := middlewares.AdminMiddleware(exprRepo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(1))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestAdminMiddleware_RegularUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	mock.ExpectQuery("SELECT id, login, password_hash, role, weight FROM users WHERE id = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "weight"}).
+			AddRow(2, "user", "hash", models.RoleUser, 1))
+
+	handler := middlewares.AdminMiddleware(exprRepo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not be called for a regular user")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(2))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAdminMiddleware_MissingUserID(t *testing.T) {
+	db, _, _ := sqlmock.New()
+	exprRepo := &repository.ExpressionModel{DB: db}
+
+	handler := middlewares.AdminMiddleware(exprRepo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not be called without user ID")
+	}))
+
+	req, _ := http.NewRequest("GET", "/api/v1/admin/users", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -47,6 +47,25 @@ func RunHTTPOrchestrator(exprRepo *repository.ExpressionModel) {
 	protected.HandleFunc("/api/v1/calculate", handlers.RegHandler(exprRepo)).Methods("POST")
 	protected.HandleFunc("/api/v1/expressions", handlers.ListHandler(Service, exprRepo)).Methods("GET")
 	protected.HandleFunc("/api/v1/expressions/{id}", handlers.ResultHandler(Service, exprRepo)).Methods("GET")
+	protected.HandleFunc("/api/v1/expressions/{id}/events", handlers.EventsHandler(exprRepo)).Methods("GET")
+	protected.HandleFunc("/api/v1/expressions/{id}/tasks", handlers.TasksHandler(exprRepo)).Methods("GET")
+	protected.HandleFunc("/api/v1/expressions/{id}/deliveries", handlers.DeliveriesHandler(exprRepo)).Methods("GET")
+	protected.HandleFunc("/api/v1/webhook", handlers.WebhookHandler(exprRepo)).Methods("GET")
+	protected.HandleFunc("/api/v1/webhook", handlers.SetWebhookHandler(exprRepo)).Methods("PUT")
+
+	admin := protected.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(middlewares.AdminMiddleware(exprRepo))
+
+	admin.HandleFunc("/users", handlers.AdminUsersHandler(exprRepo)).Methods("GET")
+	admin.HandleFunc("/users/{id}/weight", handlers.UserWeightHandler(exprRepo)).Methods("PUT")
+	admin.HandleFunc("/users/{id}/quota", handlers.UserQuotaHandler(exprRepo)).Methods("GET")
+	admin.HandleFunc("/users/{id}/quota", handlers.SetUserQuotaHandler(exprRepo)).Methods("PUT")
+	admin.HandleFunc("/cache", handlers.FlushCacheHandler(exprRepo)).Methods("DELETE")
+	admin.HandleFunc("/agents", handlers.AdminAgentsHandler(exprRepo)).Methods("GET")
+	admin.HandleFunc("/queue", handlers.QueueStatusHandler(exprRepo)).Methods("GET")
+	admin.HandleFunc("/queue/pause", handlers.PauseQueueHandler(exprRepo)).Methods("POST")
+	admin.HandleFunc("/queue/resume", handlers.ResumeQueueHandler(exprRepo)).Methods("POST")
+	admin.HandleFunc("/queue/drain", handlers.DrainQueueHandler(exprRepo)).Methods("POST")
 
 	log.Printf("HTTP orchestrator starting on %s", addr)
 	err := http.ListenAndServe(addr, router)
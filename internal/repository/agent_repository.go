@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// RegisterAgent записывает агента в базу. Повторная регистрация обновляет сведения об агенте,
+// но сохраняет время первой регистрации
+func (e *ExpressionModel) RegisterAgent(agent *models.Agent) error {
+	now := e.now().UnixMilli()
+
+	_, err := e.DB.Exec(`
+	INSERT INTO agents (id, hostname, version, workers, operations, registered_at, last_seen)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		hostname = excluded.hostname,
+		version = excluded.version,
+		workers = excluded.workers,
+		operations = excluded.operations,
+		last_seen = excluded.last_seen
+	`,
+		agent.ID,
+		agent.Hostname,
+		agent.Version,
+		agent.Workers,
+		strings.Join(agent.Operations, ","),
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register agent %s: %v", agent.ID, err)
+	}
+
+	return nil
+}
+
+// TouchAgent отмечает, что агент на связи
+func (e *ExpressionModel) TouchAgent(agentID string) error {
+	res, err := e.DB.Exec("UPDATE agents SET last_seen = ? WHERE id = ?", e.now().UnixMilli(), agentID)
+	if err != nil {
+		return fmt.Errorf("failed to update agent %s: %v", agentID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update agent %s: %v", agentID, err)
+	}
+	if affected == 0 {
+		return models.ErrorAgentNotFound
+	}
+
+	return nil
+}
+
+// ListAgents возвращает всех агентов с их состоянием, текущими тасками и количеством тасок,
+// посчитанных за последнюю минуту. Агент, не выходивший на связь дольше offlineAfter, считается offline
+func (e *ExpressionModel) ListAgents(offlineAfter time.Duration) ([]models.Agent, error) {
+	rows, err := e.DB.Query(`
+	SELECT id, hostname, version, workers, operations, registered_at, last_seen
+	FROM agents
+	ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %v", err)
+	}
+	defer rows.Close()
+
+	agents := []models.Agent{}
+	index := make(map[string]int)
+
+	for rows.Next() {
+		var (
+			agent                  models.Agent
+			operations             string
+			registeredAt, lastSeen int64
+		)
+		err := rows.Scan(&agent.ID, &agent.Hostname, &agent.Version, &agent.Workers, &operations, &registeredAt, &lastSeen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan agent: %v", err)
+		}
+
+		agent.Operations = []string{}
+		if operations != "" {
+			agent.Operations = strings.Split(operations, ",")
+		}
+		agent.RegisteredAt = time.UnixMilli(registeredAt)
+		agent.LastSeen = time.UnixMilli(lastSeen)
+		agent.CurrentTasks = []int{}
+
+		index[agent.ID] = len(agents)
+		agents = append(agents, agent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list agents: %v", err)
+	}
+	rows.Close()
+
+	taskRows, err := e.DB.Query(
+		"SELECT agent_id, id FROM tasks WHERE status = ? AND agent_id IS NOT NULL ORDER BY id",
+		models.StatusInProcess,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent tasks: %v", err)
+	}
+	defer taskRows.Close()
+
+	for taskRows.Next() {
+		var agentID string
+		var taskID int
+		if err := taskRows.Scan(&agentID, &taskID); err != nil {
+			return nil, fmt.Errorf("failed to scan agent task: %v", err)
+		}
+		if i, ok := index[agentID]; ok {
+			agents[i].CurrentTasks = append(agents[i].CurrentTasks, taskID)
+		}
+	}
+	if err := taskRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list agent tasks: %v", err)
+	}
+	taskRows.Close()
+
+	now := e.now()
+
+	throughputRows, err := e.DB.Query(
+		"SELECT agent_id, COUNT(*) FROM tasks WHERE agent_id IS NOT NULL AND finished_at > ? GROUP BY agent_id",
+		now.Add(-time.Minute).UnixMilli(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count agent throughput: %v", err)
+	}
+	defer throughputRows.Close()
+
+	for throughputRows.Next() {
+		var agentID string
+		var count int
+		if err := throughputRows.Scan(&agentID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan agent throughput: %v", err)
+		}
+		if i, ok := index[agentID]; ok {
+			agents[i].Throughput = count
+		}
+	}
+	if err := throughputRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count agent throughput: %v", err)
+	}
+
+	for i := range agents {
+		switch {
+		case now.Sub(agents[i].LastSeen) > offlineAfter:
+			agents[i].State = models.AgentStateOffline
+		case len(agents[i].CurrentTasks) > 0:
+			agents[i].State = models.AgentStateBusy
+		default:
+			agents[i].State = models.AgentStateIdle
+		}
+	}
+
+	return agents, nil
+}
+
+// LiveWorkers возвращает суммарное число воркеров у агентов, выходивших на связь за последние offlineAfter
+func (e *ExpressionModel) LiveWorkers(offlineAfter time.Duration) (int, error) {
+	var workers int
+	err := e.DB.QueryRow(
+		"SELECT COALESCE(SUM(workers), 0) FROM agents WHERE last_seen >= ?",
+		e.now().Add(-offlineAfter).UnixMilli(),
+	).Scan(&workers)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count live workers: %v", err)
+	}
+
+	return workers, nil
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
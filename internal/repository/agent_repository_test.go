@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentRegistry(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Now()
+	repo.Clock = func() time.Time { return now }
+
+	assert.ErrorIs(t, repo.TouchAgent("agent-1"), models.ErrorAgentNotFound)
+
+	require.NoError(t, repo.RegisterAgent(&models.Agent{ID: "agent-1", Hostname: "host-1", Version: "1.0", Workers: 2, Operations: []string{"+"}}))
+	require.NoError(t, repo.RegisterAgent(&models.Agent{ID: "agent-2", Hostname: "host-2", Version: "1.0", Workers: 1}))
+
+	registeredAt := now
+	now = now.Add(10 * time.Second)
+	require.NoError(t, repo.RegisterAgent(&models.Agent{ID: "agent-1", Hostname: "host-1", Version: "1.1", Workers: 3, Operations: []string{"+", "*"}}))
+
+	agents, err := repo.ListAgents(5 * time.Second)
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+
+	assert.Equal(t, "agent-1", agents[0].ID)
+	assert.Equal(t, "1.1", agents[0].Version)
+	assert.Equal(t, 3, agents[0].Workers)
+	assert.Equal(t, []string{"+", "*"}, agents[0].Operations)
+	assert.Equal(t, registeredAt.UnixMilli(), agents[0].RegisteredAt.UnixMilli(), "re-registration must keep the original registration time")
+	assert.Equal(t, models.AgentStateIdle, agents[0].State)
+
+	assert.Equal(t, "agent-2", agents[1].ID)
+	assert.Equal(t, []string{}, agents[1].Operations)
+	assert.Equal(t, models.AgentStateOffline, agents[1].State)
+
+	workers, err := repo.LiveWorkers(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, workers, "offline agents must not count as live capacity")
+
+	require.NoError(t, repo.TouchAgent("agent-2"))
+	agents, err = repo.ListAgents(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, models.AgentStateIdle, agents[1].State)
+}
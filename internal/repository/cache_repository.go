@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// GetCachedResult возвращает еще не устаревший результат выражения с ключом key
+func (e *ExpressionModel) GetCachedResult(key string) (float64, bool, error) {
+	var result float64
+
+	err := e.DB.QueryRow(
+		"SELECT result FROM result_cache WHERE cache_key = ? AND expires_at > ?",
+		key,
+		e.now().UnixMilli(),
+	).Scan(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get cached result: %v", err)
+	}
+
+	return result, true, nil
+}
+
+// InsertCachedExpression записывает выражение, ответ на которое взят из кэша. Такое выражение сразу считается
+// посчитанным, таски для него не создаются
+func (e *ExpressionModel) InsertCachedExpression(expr *models.Expression, key string, result float64) (int, error) {
+	query := `
+	INSERT INTO expressions (user_id, expression, status, result, priority, cache_key, callback_url, created_at, started_at, finished_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := e.now()
+	id, err := e.insert(query, expr.UserID, expr.Expression, models.StatusResolved, result, expr.Priority, key,
+		expr.CallbackURL, now.UnixMilli(), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	expr.ID = id
+	expr.Status = models.StatusResolved
+	expr.Result = result
+	expr.CreatedAt, expr.StartedAt, expr.FinishedAt = &now, &now, &now
+
+	e.enqueueWebhook(expr.ID)
+	return id, nil
+}
+
+// SetExpressionCacheKey помечает выражение ключом кэша: когда оно будет посчитано, результат попадет в кэш
+func (e *ExpressionModel) SetExpressionCacheKey(exprID int, key string) error {
+	_, err := e.DB.Exec("UPDATE expressions SET cache_key = ? WHERE id = ?", key, exprID)
+	if err != nil {
+		return fmt.Errorf("failed to set cache key of expression ID-%d: %v", exprID, err)
+	}
+	return nil
+}
+
+// FlushResultCache удаляет все записи кэша результатов и возвращает их количество
+func (e *ExpressionModel) FlushResultCache() (int, error) {
+	res, err := e.DB.Exec("DELETE FROM result_cache")
+	if err != nil {
+		return 0, fmt.Errorf("failed to flush result cache: %v", err)
+	}
+
+	flushed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to flush result cache: %v", err)
+	}
+
+	return int(flushed), nil
+}
+
+// cacheResult сохраняет результат выражения в кэш, если выражение было отправлено с использованием кэша
+func (e *ExpressionModel) cacheResult(exprID int, result float64) error {
+	_, err := e.DB.Exec(`
+	INSERT INTO result_cache (cache_key, result, expires_at)
+	SELECT cache_key, CAST(? AS DOUBLE PRECISION), CAST(? AS BIGINT) FROM expressions WHERE id = ? AND cache_key IS NOT NULL
+	ON CONFLICT(cache_key) DO UPDATE SET result = excluded.result, expires_at = excluded.expires_at
+	`,
+		result,
+		e.now().Add(e.ResultCacheTTL).UnixMilli(),
+		exprID,
+	)
+	return err
+}
@@ -0,0 +1,67 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCache(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Now()
+	repo.Clock = func() time.Time { return now }
+	repo.ResultCacheTTL = time.Minute
+
+	key := "float64:(2+2)"
+
+	_, ok, err := repo.GetCachedResult(key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	exprID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "2+2"})
+	assert.NoError(t, repo.SetExpressionCacheKey(exprID, key))
+	assert.NoError(t, repo.UpdateExpressionResult(exprID, 4, ""))
+
+	result, ok, err := repo.GetCachedResult(key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, result)
+
+	cachedID, err := repo.InsertCachedExpression(&models.Expression{UserID: 2, Expression: "2 + 2"}, key, result)
+	assert.NoError(t, err)
+	expr, _ := repo.GetExpression(cachedID)
+	assert.Equal(t, models.StatusResolved, expr.Status)
+	assert.Equal(t, 4.0, expr.Result)
+	assert.Equal(t, 2, expr.UserID)
+
+	now = now.Add(2 * time.Minute)
+	_, ok, err = repo.GetCachedResult(key)
+	assert.NoError(t, err)
+	assert.False(t, ok, "expired entries must not be returned")
+
+	flushed, err := repo.FlushResultCache()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, flushed)
+}
+
+func TestResultCache_FailedAndUnmarkedExpressionsAreNotCached(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	repo.ResultCacheTTL = time.Minute
+
+	failedID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1/0"})
+	assert.NoError(t, repo.SetExpressionCacheKey(failedID, "float64:(1/0)"))
+	assert.NoError(t, repo.UpdateExpressionResult(failedID, 0, "division by zero"))
+
+	plainID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "3+3"})
+	assert.NoError(t, repo.UpdateExpressionResult(plainID, 6, ""))
+
+	flushed, err := repo.FlushResultCache()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, flushed)
+}
@@ -6,8 +6,37 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
+const (
+	// DriverSQLite - база в файле SQLite, используется по умолчанию
+	DriverSQLite = "sqlite"
+
+	// DriverPostgres - база PostgreSQL
+	DriverPostgres = "postgres"
+
+	// DriverSQLiteMemory - база SQLite в памяти процесса с той же схемой, что и у файла, поэтому работают все
+	// HTTP обработчики. Данные пропадают при остановке оркестратора
+	DriverSQLiteMemory = "sqlite-memory"
+)
+
+// Open открывает базу выбранным драйвером и создаёт необходимые таблицы.
+// Для sqlite dsn - путь к файлу базы, для postgres - URL подключения, для sqlite-memory dsn не используется
+func Open(driverName, dsn string) (*sql.DB, error) {
+	switch driverName {
+	case "", DriverSQLite:
+		return InitDB(dsn)
+	case DriverPostgres:
+		return InitPostgres(dsn)
+	case DriverSQLiteMemory:
+		return InitMemoryDB()
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, expected %s, %s or %s",
+			driverName, DriverSQLite, DriverPostgres, DriverSQLiteMemory)
+	}
+}
+
 // InitDB открывает соединение с базой и создаёт необходимые таблицы
 func InitDB(path string) (*sql.DB, error) {
 	dir := filepath.Dir(path)
@@ -21,7 +50,19 @@ func InitDB(path string) (*sql.DB, error) {
 
 	log.Printf("Database path: %s", path)
 
-	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_sync=NORMAL&_foreign_keys=ON", path)
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_sync=NORMAL&_foreign_keys=ON&_pragma=busy_timeout(5000)", path)
+	return initSQLite(dsn)
+}
+
+// InitMemoryDB создаёт базу SQLite в памяти процесса, без файла на диске. База живет,
+// пока открыто ее единственное соединение, поэтому подходит для демонстрации и тестов
+func InitMemoryDB() (*sql.DB, error) {
+	log.Print("Database: in memory, data will be lost on shutdown")
+	return initSQLite("file::memory:?_pragma=busy_timeout(5000)")
+}
+
+// initSQLite открывает базу SQLite по dsn и создаёт необходимые таблицы
+func initSQLite(dsn string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", dsn)
 
 	if err != nil {
@@ -32,49 +73,26 @@ func InitDB(path string) (*sql.DB, error) {
 	db.SetMaxIdleConns(1)
 	db.SetConnMaxLifetime(0)
 
-	createExpressions := `
- 	CREATE TABLE IF NOT EXISTS expressions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER,
-		expression TEXT NOT NULL,
-		status TEXT NOT NULL,
-		result FLOAT64 DEFAULT 0,
-		error_message TEXT DEFAULT ""
- 	);`
-	_, err = db.Exec(createExpressions)
-	if err != nil {
-		return nil, fmt.Errorf("error creating expressions table: %v", err)
-	}
+	for _, table := range schema {
+		if _, err := db.Exec(table.create(sqliteTypes)); err != nil {
+			return nil, fmt.Errorf("error creating %s table: %v", table.name, err)
+		}
 
-	createTasks := `
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		expressionID INTEGER NOT NULL,
-		arg1 TEXT NOT NULL,
-		arg2 TEXT NOT NULL,
-		prev_task_id1 INTEGER DEFAULT 0,
-		prev_task_id2 INTEGER DEFAULT 0,
-		operation TEXT NOT NULL,
-		status TEXT,
-		result FLOAT,
-		error_message TEXT DEFAULT "",
-		FOREIGN KEY(expressionID) REFERENCES expressions(id)
-	);`
-	_, err = db.Exec(createTasks)
-	if err != nil {
-		return nil, fmt.Errorf("error creating tasks table: %v", err)
+		if err := ensureColumns(db, table.name, table.lateColumns(sqliteTypes)); err != nil {
+			return nil, err
+		}
+
+		for _, index := range table.indexes {
+			if _, err := db.Exec(index); err != nil {
+				return nil, fmt.Errorf("error creating %s table: %v", table.name, err)
+			}
+		}
 	}
 
-	createUsers := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		login TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-	_, err = db.Exec(createUsers)
-	if err != nil {
-		return nil, fmt.Errorf("error creating users table: %v", err)
+	for _, statement := range uniqueWebhookDeliveries {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, fmt.Errorf("error creating webhook_deliveries index: %v", err)
+		}
 	}
 
 	err = db.Ping()
@@ -85,3 +103,51 @@ func InitDB(path string) (*sql.DB, error) {
 	log.Print("Successful connection to the database")
 	return db, nil
 }
+
+// ensureColumns добавляет в уже существующую таблицу колонки, появившиеся в схеме позже
+func ensureColumns(db *sql.DB, table string, columns map[string]string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("error reading %s table info: %v", table, err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("error reading %s table info: %v", table, err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading %s table info: %v", table, err)
+	}
+	rows.Close()
+
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if existing[name] {
+			continue
+		}
+		_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, columns[name]))
+		if err != nil {
+			return fmt.Errorf("error adding column %s.%s: %v", table, name, err)
+		}
+		log.Printf("Added column %s.%s", table, name)
+	}
+
+	return nil
+}
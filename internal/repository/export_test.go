@@ -0,0 +1,9 @@
+package repository
+
+import "database/sql/driver"
+
+// PostgresDriver оборачивает драйвер так же, как драйвер Postgres оркестратора, чтобы тесты
+// видели запросы с уже переписанными плейсхолдерами
+func PostgresDriver(d driver.Driver) driver.Driver {
+	return rebindDriver{d}
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 )
@@ -13,11 +15,96 @@ import (
 type ExpressionModel struct {
 	DB *sql.DB
 	Mu sync.Mutex
+
+	// AgingInterval - за каждый такой интервал ожидания приоритет таски повышается на единицу.
+	// Если не задан, используется DefaultAgingInterval
+	AgingInterval time.Duration
+
+	// Clock возвращает текущее время. Если не задан, используется time.Now
+	Clock func() time.Time
+
+	// ResultCacheTTL - время жизни результата в кэше. Если не задано, результаты в кэш не попадают
+	ResultCacheTTL time.Duration
+
+	// FIFODispatch выдает таски в порядке создания, не учитывая их критический путь
+	FIFODispatch bool
+
+	// Driver - драйвер базы, к которой подключен DB (DriverSQLite или DriverPostgres). Пустой означает SQLite.
+	// NewExpressionModel определяет его сам
+	Driver string
+
+	schedulerOnce sync.Once
+	scheduler     *fairScheduler
+
+	ready ReadyNotifier
 }
 
-// NewExpressionModel создает экземпляр ExpressionModel
+// DefaultAgingInterval - интервал старения приоритета тасок по умолчанию
+const DefaultAgingInterval = 30 * time.Second
+
+// NewExpressionModel создает экземпляр ExpressionModel. Диалект SQL выбирается по драйверу, которым открыта db
 func NewExpressionModel(db *sql.DB) *ExpressionModel {
-	return &ExpressionModel{DB: db}
+	model := &ExpressionModel{DB: db, Driver: DriverSQLite}
+	if _, ok := db.Driver().(rebindDriver); ok {
+		model.Driver = DriverPostgres
+	}
+	return model
+}
+
+func (e *ExpressionModel) now() time.Time {
+	if e.Clock != nil {
+		return e.Clock()
+	}
+	return time.Now()
+}
+
+// dbExecutor - *sql.DB или *sql.Tx
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insert выполняет INSERT и возвращает ID созданной записи
+func (e *ExpressionModel) insert(query string, args ...interface{}) (int, error) {
+	return e.insertWith(e.DB, query, args...)
+}
+
+// insertWith выполняет INSERT через db или транзакцию и возвращает ID созданной записи.
+// Драйвер Postgres не поддерживает LastInsertId, поэтому в нем ID возвращается через RETURNING
+func (e *ExpressionModel) insertWith(q dbExecutor, query string, args ...interface{}) (int, error) {
+	if e.Driver == DriverPostgres {
+		var id int
+		if err := q.QueryRow(query+" RETURNING id", args...).Scan(&id); err != nil {
+			return 0, fmt.Errorf("%w: %v", models.ErrorCreatingDatabaseRecord, err)
+		}
+		return id, nil
+	}
+
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", models.ErrorCreatingDatabaseRecord, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", models.ErrorReceivingID, err)
+	}
+
+	return int(id), nil
+}
+
+func (e *ExpressionModel) agingInterval() time.Duration {
+	if e.AgingInterval > 0 {
+		return e.AgingInterval
+	}
+	return DefaultAgingInterval
+}
+
+func (e *ExpressionModel) fairScheduler() *fairScheduler {
+	e.schedulerOnce.Do(func() {
+		e.scheduler = newFairScheduler()
+	})
+	return e.scheduler
 }
 
 // AreAllTasksCompleted проверяет, все ли таски данного выражения выполнены
@@ -71,29 +158,41 @@ func (e *ExpressionModel) CalculateExpressionResult(exprID int) (float64, string
 
 // Insert записывает мат выражение в таблицу БД
 func (e *ExpressionModel) Insert(expression string, userID int) (int, error) {
-	query := "INSERT INTO expressions (user_id, expression, status, result) VALUES (?, ?, ?, ?)"
+	query := "INSERT INTO expressions (user_id, expression, status, result, created_at) VALUES (?, ?, ?, ?, ?)"
 
-	result, err := e.DB.Exec(query, userID, expression, models.StatusWait, 0)
-	if err != nil {
-		return 0, fmt.Errorf("%w: %v", models.ErrorCreatingDatabaseRecord, err)
-	}
+	return e.insert(query, userID, expression, models.StatusWait, 0, e.now().UnixMilli())
+}
 
-	id, err := result.LastInsertId()
+// InsertExpression записывает мат выражение вместе с его параметрами (приоритетом и т.д.) в таблицу БД
+func (e *ExpressionModel) InsertExpression(expr *models.Expression) (int, error) {
+	return e.insertExpression(e.DB, expr)
+}
+
+// insertExpression записывает выражение через db или транзакцию
+func (e *ExpressionModel) insertExpression(q dbExecutor, expr *models.Expression) (int, error) {
+	query := "INSERT INTO expressions (user_id, expression, status, result, priority, deadline, callback_url, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	createdAt := e.now()
+	id, err := e.insertWith(q, query, expr.UserID, expr.Expression, models.StatusWait, 0, expr.Priority, nullableTime(expr.Deadline),
+		expr.CallbackURL, createdAt.UnixMilli())
 	if err != nil {
-		return 0, fmt.Errorf("%w: %v", models.ErrorReceivingID, err)
+		return 0, err
 	}
 
-	return int(id), nil
+	expr.ID = id
+	expr.CreatedAt = &createdAt
+	return id, nil
 }
 
 // GetExpression возвращает из базы данных соответствующее выражение
 func (e *ExpressionModel) GetExpression(exprID int) (*models.Expression, error) {
 	query := `
-	SELECT id, user_id, expression, status, result, error_message
+	SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at
 	FROM expressions
 	WHERE id = ?
 	`
 	var expr models.Expression
+	var deadline, createdAt, startedAt, finishedAt sql.NullInt64
 
 	err := e.DB.QueryRow(query, exprID).Scan(
 		&expr.ID,
@@ -102,14 +201,97 @@ func (e *ExpressionModel) GetExpression(exprID int) (*models.Expression, error)
 		&expr.Status,
 		&expr.Result,
 		&expr.ErrorMessage,
+		&expr.Priority,
+		&deadline,
+		&createdAt,
+		&startedAt,
+		&finishedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("fail to get expression ID-%d: %w", exprID, models.ErrorExpressionNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("fail to get expression ID-%d: %v", exprID, err)
 	}
+	expr.Deadline = timeFromMillis(deadline)
+	expr.CreatedAt = timeFromMillis(createdAt)
+	expr.StartedAt = timeFromMillis(startedAt)
+	expr.FinishedAt = timeFromMillis(finishedAt)
 
 	return &expr, nil
 }
 
+// ListExpressions возвращает выражения пользователя, подходящие под filter, в заданном в нем порядке
+func (e *ExpressionModel) ListExpressions(userID int, filter models.ExpressionFilter) ([]models.Expression, error) {
+	query := `
+	SELECT id, user_id, expression, status, result, error_message, priority, deadline, created_at, started_at, finished_at
+	FROM expressions
+	WHERE user_id = ?
+	`
+	args := []interface{}{userID}
+
+	for _, bound := range []struct {
+		column string
+		op     string
+		value  *time.Time
+	}{
+		{"created_at", ">=", filter.CreatedAfter},
+		{"created_at", "<", filter.CreatedBefore},
+		{"started_at", ">=", filter.StartedAfter},
+		{"started_at", "<", filter.StartedBefore},
+		{"finished_at", ">=", filter.FinishedAfter},
+		{"finished_at", "<", filter.FinishedBefore},
+	} {
+		if bound.value != nil {
+			query += fmt.Sprintf("\tAND %s %s ?\n", bound.column, bound.op)
+			args = append(args, bound.value.UnixMilli())
+		}
+	}
+
+	order := "id"
+	switch filter.SortBy {
+	case "":
+	case "created_at", "started_at", "finished_at":
+		// выражения, у которых еще нет отметки времени, идут в конце
+		order = fmt.Sprintf("%s IS NULL, %s", filter.SortBy, filter.SortBy)
+		if filter.Descending {
+			order += " DESC"
+		}
+		order += ", id"
+	default:
+		return nil, fmt.Errorf("%w: %s", models.ErrorInvalidSortKey, filter.SortBy)
+	}
+	query += "\tORDER BY " + order
+
+	rows, err := e.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expressions: %v", err)
+	}
+	defer rows.Close()
+
+	var expressions []models.Expression
+	var result string
+
+	for rows.Next() {
+		var expr models.Expression
+		var deadline, createdAt, startedAt, finishedAt sql.NullInt64
+		err := rows.Scan(&expr.ID, &expr.UserID, &expr.Expression, &expr.Status, &result, &expr.ErrorMessage, &expr.Priority,
+			&deadline, &createdAt, &startedAt, &finishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expression: %v", err)
+		}
+		expr.Deadline = timeFromMillis(deadline)
+		expr.CreatedAt = timeFromMillis(createdAt)
+		expr.StartedAt = timeFromMillis(startedAt)
+		expr.FinishedAt = timeFromMillis(finishedAt)
+
+		expr.Result, _ = strconv.ParseFloat(result, 64)
+		expressions = append(expressions, expr)
+	}
+
+	return expressions, rows.Err()
+}
+
 // UpdateExpressionResult обновляет результат и статус выражения
 func (e *ExpressionModel) UpdateExpressionResult(exprID int, result float64, errorMessage string) error {
 	var status string = models.StatusResolved
@@ -119,28 +301,43 @@ func (e *ExpressionModel) UpdateExpressionResult(exprID int, result float64, err
 	}
 	query := `
         UPDATE expressions 
-        SET result = ?, status = ?, error_message = ?
+        SET result = ?, status = ?, error_message = ?, finished_at = ?
         WHERE id = ?
     `
-	_, err := e.DB.Exec(query, result, status, errorMessage, exprID)
+	_, err := e.DB.Exec(query, result, status, errorMessage, e.now().UnixMilli(), exprID)
 	if err != nil {
 		return fmt.Errorf("failed to update expression result: %v", err)
 	}
 
+	e.enqueueWebhook(exprID)
+
 	if errorMessage != "" {
 		log.Printf("update result for expression ID-%d: %v\nerror message: %v", exprID, result, errorMessage)
-	} else {
-		log.Printf("update result for expression ID-%d: %v", exprID, result)
+		return nil
+	}
+
+	log.Printf("update result for expression ID-%d: %v", exprID, result)
+
+	if e.ResultCacheTTL > 0 {
+		if err := e.cacheResult(exprID, result); err != nil {
+			log.Printf("failed to cache result of expression ID-%d: %v", exprID, err)
+		}
 	}
 
 	return nil
 }
 
-// UpdateStatus устанавливает актуальный статус выражения в БД
+// UpdateStatus устанавливает актуальный статус выражения в БД. Уведомление о завершении не ставится:
+// так выражение проваливается, только если его отклонили сразу при приеме, и клиент уже получил ошибку в ответе
 func (e *ExpressionModel) UpdateStatus(id int, status string) {
 	query := "UPDATE expressions SET status = ? WHERE id = ?"
+	args := []interface{}{status, id}
+	if status == models.StatusResolved || status == models.StatusFailed {
+		query = "UPDATE expressions SET status = ?, finished_at = ? WHERE id = ?"
+		args = []interface{}{status, e.now().UnixMilli(), id}
+	}
 
-	_, err := e.DB.Exec(query, status, id)
+	_, err := e.DB.Exec(query, args...)
 	if err != nil {
 		log.Println(err)
 	}
@@ -155,3 +352,95 @@ func (e *ExpressionModel) SetResult(id int, result float64) {
 		log.Println(err)
 	}
 }
+
+// ExpireExpressions проваливает незавершенные выражения, дедлайн которых наступил к моменту now,
+// и снимает с раздачи их оставшиеся таски. Возвращает ID просроченных выражений
+func (e *ExpressionModel) ExpireExpressions(now time.Time) ([]int, error) {
+	rows, err := e.DB.Query(
+		"SELECT id FROM expressions WHERE status IN (?, ?) AND deadline IS NOT NULL AND deadline <= ?",
+		models.StatusWait,
+		models.StatusInProcess,
+		now.UnixMilli(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired expressions: %v", err)
+	}
+	defer rows.Close()
+
+	var expired []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expired expression: %v", err)
+		}
+		expired = append(expired, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find expired expressions: %v", err)
+	}
+	rows.Close()
+
+	for _, id := range expired {
+		_, err := e.DB.Exec(`
+		INSERT INTO task_events (task_id, expression_id, status, agent_id, error_message, created_at)
+		SELECT id, expressionID, ?, agent_id, ?, CAST(? AS BIGINT) FROM tasks WHERE expressionID = ? AND status IN (?, ?, ?)
+		`,
+			models.StatusFailed,
+			models.ErrorDeadlineExceeded.Error(),
+			now.UnixMilli(),
+			id,
+			models.StatusNew,
+			models.StatusWait,
+			models.StatusInProcess,
+		)
+		if err != nil {
+			log.Printf("failed to record events for tasks of expression ID-%d: %v", id, err)
+		}
+
+		_, err = e.DB.Exec(
+			"UPDATE tasks SET status = ?, error_message = ?, finished_at = ? WHERE expressionID = ? AND status IN (?, ?, ?)",
+			models.StatusFailed,
+			models.ErrorDeadlineExceeded.Error(),
+			now.UnixMilli(),
+			id,
+			models.StatusNew,
+			models.StatusWait,
+			models.StatusInProcess,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to withdraw tasks of expression ID-%d: %v", id, err)
+		}
+
+		_, err = e.DB.Exec(
+			"UPDATE expressions SET status = ?, error_message = ?, finished_at = ? WHERE id = ?",
+			models.StatusFailed,
+			models.ErrorDeadlineExceeded.Error(),
+			now.UnixMilli(),
+			id,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expire expression ID-%d: %v", id, err)
+		}
+
+		log.Printf("expression ID-%d failed: %v", id, models.ErrorDeadlineExceeded)
+
+		e.enqueueWebhook(id)
+	}
+
+	return expired, nil
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
+
+func timeFromMillis(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := time.UnixMilli(ms.Int64)
+	return &t
+}
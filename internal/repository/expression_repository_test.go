@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 	"github.com/stretchr/testify/assert"
@@ -75,3 +76,131 @@ func TestUpdateStatus_NonExistentExpression(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, expr)
 }
+
+func TestExpireExpressions(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+
+	expiredID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1+1", Deadline: &past})
+	doneTaskID, _ := repo.InsertTask(&models.Task{ExpressionID: expiredID, Operation: "+", Status: models.StatusResolved})
+	pendingTaskID, _ := repo.InsertTask(&models.Task{ExpressionID: expiredID, Operation: "+", Status: models.StatusWait})
+
+	aliveID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "2+2", Deadline: &future})
+	aliveTaskID, _ := repo.InsertTask(&models.Task{ExpressionID: aliveID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	task, _, err := repo.GetTask(models.TaskFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, aliveTaskID, task.ID, "tasks of an expired expression must not be dispatched")
+	assert.NotNil(t, task.Deadline)
+
+	expired, err := repo.ExpireExpressions(now)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{expiredID}, expired)
+
+	expr, _ := repo.GetExpression(expiredID)
+	assert.Equal(t, models.StatusFailed, expr.Status)
+	assert.Equal(t, models.ErrorDeadlineExceeded.Error(), expr.ErrorMessage)
+
+	doneTask, _ := repo.GetTaskByID(doneTaskID)
+	assert.Equal(t, models.StatusResolved, doneTask.Status)
+	pendingTask, _ := repo.GetTaskByID(pendingTaskID)
+	assert.Equal(t, models.StatusFailed, pendingTask.Status)
+
+	assert.NoError(t, repo.UpdateTaskResult(pendingTaskID, 2, ""))
+	pendingTask, _ = repo.GetTaskByID(pendingTaskID)
+	assert.Equal(t, models.StatusFailed, pendingTask.Status, "late results of withdrawn tasks must be ignored")
+
+	expr, _ = repo.GetExpression(aliveID)
+	assert.Equal(t, models.StatusWait, expr.Status)
+}
+
+func TestExpressionTimings(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	start := time.Now().Truncate(time.Millisecond)
+	now := start
+	repo.Clock = func() time.Time { return now }
+
+	exprID, _ := repo.Insert("1+2", 1)
+	taskID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	expr, _ := repo.GetExpression(exprID)
+	assert.Equal(t, start, *expr.CreatedAt)
+	assert.Nil(t, expr.StartedAt)
+	assert.Nil(t, expr.FinishedAt)
+
+	now = start.Add(2 * time.Second)
+	_, _, err := repo.GetTask(models.TaskFilter{})
+	assert.NoError(t, err)
+
+	now = start.Add(3 * time.Second)
+	_, err = repo.RequeueTask(taskID)
+	assert.NoError(t, err)
+	task, _ := repo.GetTaskByID(taskID)
+	assert.Nil(t, task.StartedAt, "requeued task must start over")
+
+	now = start.Add(5 * time.Second)
+	_, _, err = repo.GetTask(models.TaskFilter{})
+	assert.NoError(t, err)
+
+	now = start.Add(6 * time.Second)
+	assert.NoError(t, repo.UpdateTaskResult(taskID, 3, ""))
+
+	task, _ = repo.GetTaskByID(taskID)
+	assert.Equal(t, start, *task.CreatedAt)
+	assert.Equal(t, start.Add(5*time.Second), *task.StartedAt)
+	assert.Equal(t, start.Add(6*time.Second), *task.FinishedAt)
+
+	expr, _ = repo.GetExpression(exprID)
+	assert.Equal(t, start, *expr.CreatedAt)
+	assert.Equal(t, start.Add(2*time.Second), *expr.StartedAt, "expression starts with its first claimed task")
+	assert.Equal(t, start.Add(6*time.Second), *expr.FinishedAt)
+}
+
+func TestListExpressions_FilterAndSort(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	start := time.Now().Truncate(time.Millisecond)
+	now := start
+	repo.Clock = func() time.Time { return now }
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		now = start.Add(time.Duration(i) * time.Minute)
+		id, _ := repo.Insert("1+1", 1)
+		ids = append(ids, id)
+	}
+	now = start.Add(10 * time.Minute)
+	assert.NoError(t, repo.UpdateExpressionResult(ids[2], 2, ""))
+	now = start.Add(11 * time.Minute)
+	assert.NoError(t, repo.UpdateExpressionResult(ids[0], 2, ""))
+
+	listIDs := func(filter models.ExpressionFilter) []int {
+		expressions, err := repo.ListExpressions(1, filter)
+		assert.NoError(t, err)
+		var got []int
+		for _, expr := range expressions {
+			got = append(got, expr.ID)
+		}
+		return got
+	}
+
+	after := start.Add(time.Minute)
+	assert.Equal(t, []int{ids[1], ids[2]}, listIDs(models.ExpressionFilter{CreatedAfter: &after}))
+	assert.Equal(t, []int{ids[0]}, listIDs(models.ExpressionFilter{CreatedBefore: &after}))
+	assert.Equal(t, []int{ids[2], ids[1], ids[0]}, listIDs(models.ExpressionFilter{SortBy: "created_at", Descending: true}))
+	assert.Equal(t, []int{ids[2], ids[0], ids[1]}, listIDs(models.ExpressionFilter{SortBy: "finished_at"}),
+		"unfinished expressions go last")
+
+	finishedAfter := start.Add(10*time.Minute + time.Second)
+	assert.Equal(t, []int{ids[0]}, listIDs(models.ExpressionFilter{FinishedAfter: &finishedAfter}))
+
+	_, err := repo.ListExpressions(1, models.ExpressionFilter{SortBy: "id; DROP TABLE expressions"})
+	assert.ErrorIs(t, err, models.ErrorInvalidSortKey)
+}
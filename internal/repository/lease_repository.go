@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// AcquireLease захватывает или продлевает аренду name на ttl для holder. Аренду, которую держит
+// другой holder, можно захватить только после того, как она истекла. Возвращает false, если аренда занята
+func (e *ExpressionModel) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := e.now()
+
+	res, err := e.DB.Exec(`
+	INSERT INTO leases (name, holder, expires_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT(name) DO UPDATE SET
+		holder = excluded.holder,
+		expires_at = excluded.expires_at
+	WHERE leases.holder = excluded.holder OR leases.expires_at <= ?
+	`,
+		name,
+		holder,
+		now.Add(ttl).UnixMilli(),
+		now.UnixMilli(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %v", name, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %v", name, err)
+	}
+
+	return affected == 1, nil
+}
+
+// ReleaseLease освобождает аренду name, если ее держит holder, чтобы другой экземпляр
+// мог захватить ее, не дожидаясь истечения
+func (e *ExpressionModel) ReleaseLease(name, holder string) error {
+	_, err := e.DB.Exec("DELETE FROM leases WHERE name = ? AND holder = ?", name, holder)
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// LeaseHolder возвращает текущего владельца аренды name или пустую строку, если аренда свободна или истекла
+func (e *ExpressionModel) LeaseHolder(name string) (string, error) {
+	var holder string
+	err := e.DB.QueryRow(
+		"SELECT holder FROM leases WHERE name = ? AND expires_at > ?",
+		name,
+		e.now().UnixMilli(),
+	).Scan(&holder)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get lease %s: %v", name, err)
+	}
+
+	return holder, nil
+}
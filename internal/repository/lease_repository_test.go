@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLease(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Now()
+	repo.Clock = func() time.Time { return now }
+
+	acquired, err := repo.AcquireLease("leader", "a", 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = repo.AcquireLease("leader", "b", 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, acquired, "lease held by another instance must not be taken over")
+
+	now = now.Add(5 * time.Second)
+	acquired, err = repo.AcquireLease("leader", "a", 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, acquired, "holder must be able to renew its lease")
+
+	now = now.Add(9 * time.Second)
+	acquired, err = repo.AcquireLease("leader", "b", 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, acquired, "renewed lease must not expire early")
+
+	now = now.Add(2 * time.Second)
+	holder, err := repo.LeaseHolder("leader")
+	require.NoError(t, err)
+	assert.Empty(t, holder)
+
+	acquired, err = repo.AcquireLease("leader", "b", 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, acquired, "expired lease must be taken over")
+
+	require.NoError(t, repo.ReleaseLease("leader", "a"))
+	holder, _ = repo.LeaseHolder("leader")
+	assert.Equal(t, "b", holder, "only the holder can release the lease")
+
+	require.NoError(t, repo.ReleaseLease("leader", "b"))
+	holder, _ = repo.LeaseHolder("leader")
+	assert.Empty(t, holder)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import "sync"
+
+// ReadyNotifier оповещает подписчиков о том, что в очереди могли появиться готовые к выполнению таски.
+// Подписчик получает канал, который закрывается при следующем оповещении
+type ReadyNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// Subscribe возвращает канал, который закроется при следующем вызове Notify
+func (n *ReadyNotifier) Subscribe() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	return n.ch
+}
+
+// Notify закрывает канал текущих подписчиков
+func (n *ReadyNotifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+// TasksReady возвращает канал, который закроется, когда в очереди появятся новые таски
+// или у ожидающих тасок разрешатся зависимости. Подписываться нужно до проверки очереди, чтобы не пропустить оповещение
+func (e *ExpressionModel) TasksReady() <-chan struct{} {
+	return e.ready.Subscribe()
+}
+
+// NotifyTasksReady будит всех, кто ждет готовых тасок
+func (e *ExpressionModel) NotifyTasksReady() {
+	e.ready.Notify()
+}
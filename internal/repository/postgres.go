@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// postgresDriverName - драйвер database/sql, через который ExpressionModel работает с Postgres
+const postgresDriverName = "calculator-postgres"
+
+func init() {
+	sql.Register(postgresDriverName, rebindDriver{pq.Driver{}})
+}
+
+// InitPostgres открывает соединение с Postgres по url и создаёт необходимые таблицы
+func InitPostgres(url string) (*sql.DB, error) {
+	db, err := sql.Open(postgresDriverName, url)
+	if err != nil {
+		return nil, fmt.Errorf("error when opening database: %v", err)
+	}
+
+	if err := MigratePostgres(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error when connecting with database: %v", err)
+	}
+
+	log.Print("Successful connection to the database")
+	return db, nil
+}
+
+// MigratePostgres создаёт в базе Postgres недостающие таблицы и индексы. Схема та же, что у SQLite
+func MigratePostgres(db *sql.DB) error {
+	for _, statement := range schemaStatements(postgresTypes) {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("error creating postgres schema: %v", err)
+		}
+	}
+	return nil
+}
+
+// Rebind переписывает плейсхолдеры ? в нумерованные $1, $2, ..., которые понимает Postgres.
+// Знаки вопроса внутри строковых литералов и идентификаторов в кавычках не трогаются
+func Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var (
+		b     strings.Builder
+		n     int
+		quote rune
+	)
+	b.Grow(len(query) + 8)
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// rebindDriver оборачивает драйвер Postgres так, чтобы запросы ExpressionModel с плейсхолдерами ?
+// работали без изменений
+type rebindDriver struct {
+	driver.Driver
+}
+
+func (d rebindDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rebindConn{conn}, nil
+}
+
+type rebindConn struct {
+	driver.Conn
+}
+
+func (c *rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(Rebind(query))
+}
+
+func (c *rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, Rebind(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c *rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(ctx, Rebind(query), args)
+}
+
+func (c *rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return queryer.QueryContext(ctx, Rebind(query), args)
+}
+
+func (c *rebindConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rebindConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rebindConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *rebindConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
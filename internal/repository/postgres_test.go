@@ -0,0 +1,226 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT id FROM users WHERE login = ?", "SELECT id FROM users WHERE login = $1"},
+		{"UPDATE tasks SET status = ? WHERE id = ? AND status IN (?, ?)", "UPDATE tasks SET status = $1 WHERE id = $2 AND status IN ($3, $4)"},
+		{"SELECT '?' AS mark, \"odd?\" FROM t WHERE a = ?", "SELECT '?' AS mark, \"odd?\" FROM t WHERE a = $1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, repository.Rebind(tt.query))
+	}
+}
+
+func TestMigratePostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, object := range []string{
+		"TABLE IF NOT EXISTS expressions",
+		"TABLE IF NOT EXISTS tasks",
+		"TABLE IF NOT EXISTS users",
+		"TABLE IF NOT EXISTS user_quotas",
+		"TABLE IF NOT EXISTS result_cache",
+		"TABLE IF NOT EXISTS agents",
+		"TABLE IF NOT EXISTS task_events",
+		"INDEX IF NOT EXISTS task_events_expression_id",
+		"TABLE IF NOT EXISTS leases",
+		"TABLE IF NOT EXISTS queue_state",
+		"TABLE IF NOT EXISTS webhook_deliveries",
+		"INDEX IF NOT EXISTS webhook_deliveries_next_attempt_at",
+	} {
+		mock.ExpectExec(regexp.QuoteMeta("CREATE " + object)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	for _, statement := range []string{
+		"DELETE FROM webhook_deliveries WHERE id NOT IN",
+		"DROP INDEX IF EXISTS webhook_deliveries_expression_id",
+		"CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_expression_id_unique",
+	} {
+		mock.ExpectExec(regexp.QuoteMeta(statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	require.NoError(t, repository.MigratePostgres(db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratePostgres_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS expressions").WillReturnError(errors.New("permission denied"))
+
+	err = repository.MigratePostgres(db)
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+const postgresMockDriver = "postgres-sqlmock"
+
+var registerPostgresMock sync.Once
+
+// newPostgresMock возвращает ExpressionModel поверх sqlmock, подключенного через ту же обертку, что и драйвер Postgres,
+// поэтому ожидания записываются с плейсхолдерами $1, $2, ...
+func newPostgresMock(t *testing.T) (*repository.ExpressionModel, sqlmock.Sqlmock) {
+	mockDB, mock, err := sqlmock.NewWithDSN(t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	registerPostgresMock.Do(func() {
+		sql.Register(postgresMockDriver, repository.PostgresDriver(mockDB.Driver()))
+	})
+
+	db, err := sql.Open(postgresMockDriver, t.Name())
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	pg := repository.NewExpressionModel(db)
+	require.Equal(t, repository.DriverPostgres, pg.Driver)
+	return pg, mock
+}
+
+func TestPostgres_InsertReturnsID(t *testing.T) {
+	pg, mock := newPostgresMock(t)
+
+	mock.ExpectQuery(`INSERT INTO expressions \(.+\) VALUES \(\$1, .+, \$8\) RETURNING id`).
+		WithArgs(3, "2+2", models.StatusWait, 0, 5, nil, "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectQuery(`INSERT INTO users \(login, password_hash\) VALUES \(\$1, \$2\) RETURNING id`).
+		WithArgs("pg-user", "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO users .+ RETURNING id`).
+		WillReturnError(errors.New("duplicate key value violates unique constraint"))
+
+	expr := &models.Expression{UserID: 3, Expression: "2+2", Priority: 5}
+	id, err := pg.InsertExpression(expr)
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+	assert.Equal(t, 42, expr.ID)
+
+	user := &models.User{Login: "pg-user", PasswordHash: "hash"}
+	id, err = pg.CreateUser(user)
+	require.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.Equal(t, 7, user.ID)
+
+	_, err = pg.CreateUser(user)
+	assert.ErrorIs(t, err, models.ErrorCreatingDatabaseRecord)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_TypedPlaceholders(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	pg, mock := newPostgresMock(t)
+	pg.Clock = func() time.Time { return now }
+	pg.ResultCacheTTL = time.Minute
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET status = $1 WHERE id = $2")).
+		WithArgs(models.StatusWait, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT id, expressionID, $1, COALESCE($2, agent_id), $3, CAST($4 AS BIGINT) FROM tasks WHERE id = $5")).
+		WithArgs(models.StatusWait, nil, "", now.UnixMilli(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	pg.UpdateTaskStatus(7, models.StatusWait)
+
+	mock.ExpectExec(regexp.QuoteMeta("SET result = $1, status = $2, error_message = $3, finished_at = $4")).
+		WithArgs(4.0, models.StatusResolved, "", now.UnixMilli(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE ex.id = $1")).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expression", "status", "result", "error_message", "finished_at", "url"}).
+			AddRow(3, "2+2", models.StatusResolved, 4.0, "", now.UnixMilli(), ""))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT cache_key, CAST($1 AS DOUBLE PRECISION), CAST($2 AS BIGINT) FROM expressions WHERE id = $3")).
+		WithArgs(4.0, now.Add(time.Minute).UnixMilli(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, pg.UpdateExpressionResult(3, 4, ""))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM expressions WHERE status IN ($1, $2) AND deadline IS NOT NULL AND deadline <= $3")).
+		WithArgs(models.StatusWait, models.StatusInProcess, now.UnixMilli()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT id, expressionID, $1, agent_id, $2, CAST($3 AS BIGINT) FROM tasks WHERE expressionID = $4 AND status IN ($5, $6, $7)")).
+		WithArgs(models.StatusFailed, models.ErrorDeadlineExceeded.Error(), now.UnixMilli(), 5,
+			models.StatusNew, models.StatusWait, models.StatusInProcess).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET status = $1, error_message = $2, finished_at = $3 WHERE expressionID = $4")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE expressions SET status = $1, error_message = $2, finished_at = $3 WHERE id = $4")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE ex.id = $1")).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expression", "status", "result", "error_message", "finished_at", "url"}).
+			AddRow(5, "1+1", models.StatusFailed, 0.0, models.ErrorDeadlineExceeded.Error(), now.UnixMilli(), ""))
+
+	expired, err := pg.ExpireExpressions(now)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5}, expired)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewExpressionModel_DetectsDriver(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, repository.DriverSQLite, repository.NewExpressionModel(db).Driver)
+}
+
+func TestOpen_UnsupportedDriver(t *testing.T) {
+	_, err := repository.Open("mysql", "calc")
+	assert.ErrorContains(t, err, "unsupported database driver")
+
+	_, err = repository.Open("memory", "")
+	assert.ErrorContains(t, err, "unsupported database driver")
+}
+
+func TestOpen_SQLiteMemory(t *testing.T) {
+	db, err := repository.Open(repository.DriverSQLiteMemory, "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	exprRepo := repository.NewExpressionModel(db)
+	exprID, err := exprRepo.Insert("2+2", 1)
+	require.NoError(t, err)
+
+	expr, err := exprRepo.GetExpression(exprID)
+	require.NoError(t, err)
+	assert.Equal(t, "2+2", expr.Expression)
+}
+
+func TestPostgres_PickTaskRanksUsersInSQL(t *testing.T) {
+	pg, mock := newPostgresMock(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT paused, draining FROM queue_state")).
+		WillReturnRows(sqlmock.NewRows([]string{"paused", "draining"}))
+	mock.ExpectQuery(`ROW_NUMBER\(\) OVER \(PARTITION BY user_id ORDER BY effective_priority DESC, critical_path DESC, id\)(.|\n)+WHERE user_rank = 1 AND effective_priority = \(SELECT MAX\(effective_priority\) FROM ready\)(.|\n)+LIMIT \$9`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), models.StatusWait, models.StatusResolved, models.StatusResolved,
+			sqlmock.AnyArg(), "+", 256).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "weight"}))
+
+	task, _, err := pg.GetTask(models.TaskFilter{Operations: []string{"+"}})
+	require.NoError(t, err)
+	assert.Nil(t, task)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// GetQueueState возвращает режим работы очереди. Пока администратор его не менял, очередь работает как обычно
+func (e *ExpressionModel) GetQueueState() (models.QueueState, error) {
+	var state models.QueueState
+	err := e.DB.QueryRow("SELECT paused, draining FROM queue_state WHERE id = 1").Scan(&state.Paused, &state.Draining)
+	if err == sql.ErrNoRows {
+		return models.QueueState{}, nil
+	}
+	if err != nil {
+		return models.QueueState{}, fmt.Errorf("failed to get queue state: %v", err)
+	}
+
+	return state, nil
+}
+
+// SetQueueState меняет режим работы очереди. Состояние хранится в базе, поэтому действует на все оркестраторы.
+// Когда выдача тасок возобновляется, ожидающие агенты будятся сразу
+func (e *ExpressionModel) SetQueueState(state models.QueueState) error {
+	_, err := e.DB.Exec(`
+	INSERT INTO queue_state (id, paused, draining, updated_at)
+	VALUES (1, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		paused = excluded.paused,
+		draining = excluded.draining,
+		updated_at = excluded.updated_at
+	`,
+		state.Paused,
+		state.Draining,
+		e.now().UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set queue state: %v", err)
+	}
+
+	if !state.Paused {
+		e.NotifyTasksReady()
+	}
+	return nil
+}
+
+// GetQueueStatus возвращает режим очереди и сколько работы еще не завершено: по нему администратор
+// понимает, что после drain все начатые выражения досчитаны
+func (e *ExpressionModel) GetQueueStatus() (models.QueueStatus, error) {
+	state, err := e.GetQueueState()
+	if err != nil {
+		return models.QueueStatus{}, err
+	}
+
+	status := models.QueueStatus{QueueState: state}
+
+	err = e.DB.QueryRow(
+		"SELECT COUNT(*) FROM expressions WHERE status IN (?, ?)",
+		models.StatusWait,
+		models.StatusInProcess,
+	).Scan(&status.ActiveExpressions)
+	if err != nil {
+		return models.QueueStatus{}, fmt.Errorf("failed to count active expressions: %v", err)
+	}
+
+	err = e.DB.QueryRow(`
+	SELECT COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+	       COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0)
+	FROM tasks
+	`,
+		models.StatusWait,
+		models.StatusInProcess,
+	).Scan(&status.QueuedTasks, &status.RunningTasks)
+	if err != nil {
+		return models.QueueStatus{}, fmt.Errorf("failed to count tasks: %v", err)
+	}
+
+	return status, nil
+}
+
+// GetQueueLoad возвращает, сколько тасок ждут выдачи агентам и сколько тасок посчитано начиная с since
+func (e *ExpressionModel) GetQueueLoad(since time.Time) (int, int, error) {
+	var queued, finished int
+	err := e.DB.QueryRow(`
+	SELECT COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+	       COALESCE(SUM(CASE WHEN finished_at > ? THEN 1 ELSE 0 END), 0)
+	FROM tasks
+	`,
+		models.StatusWait,
+		since.UnixMilli(),
+	).Scan(&queued, &finished)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get queue load: %v", err)
+	}
+
+	return queued, finished, nil
+}
@@ -0,0 +1,47 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueState(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	state, err := repo.GetQueueState()
+	require.NoError(t, err)
+	assert.Equal(t, models.QueueState{}, state, "queue must work normally until an admin changes it")
+
+	exprID, _ := repo.Insert("1+2", 1)
+	_, err = repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 2, Operation: "+", Status: models.StatusWait})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SetQueueState(models.QueueState{Paused: true, Draining: true}))
+
+	task, _, err := repo.GetTask(models.TaskFilter{})
+	assert.ErrorIs(t, err, models.ErrorQueuePaused)
+	assert.Nil(t, task)
+
+	status, err := repo.GetQueueStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Paused)
+	assert.True(t, status.Draining)
+	assert.Equal(t, 1, status.ActiveExpressions)
+	assert.Equal(t, 1, status.QueuedTasks)
+	assert.Equal(t, 0, status.RunningTasks)
+
+	require.NoError(t, repo.SetQueueState(models.QueueState{Draining: true}))
+
+	task, _, err = repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	require.NotNil(t, task, "draining must keep dispatching accepted work")
+
+	status, err = repo.GetQueueStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.QueuedTasks)
+	assert.Equal(t, 1, status.RunningTasks)
+}
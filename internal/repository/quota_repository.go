@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// GetUserQuota возвращает лимиты пользователя: персональные, если администратор их задал, иначе значения по умолчанию
+func (e *ExpressionModel) GetUserQuota(userID int, defaults models.Quota) (models.Quota, error) {
+	var maxActive, maxTasks, maxQueued sql.NullInt64
+
+	query := "SELECT max_active_expressions, max_tasks_per_expression, max_queued_tasks FROM user_quotas WHERE user_id = ?"
+	err := e.DB.QueryRow(query, userID).Scan(&maxActive, &maxTasks, &maxQueued)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, fmt.Errorf("failed to get quota for user ID-%d: %v", userID, err)
+	}
+
+	quota := defaults
+	if maxActive.Valid {
+		quota.MaxActiveExpressions = int(maxActive.Int64)
+	}
+	if maxTasks.Valid {
+		quota.MaxTasksPerExpression = int(maxTasks.Int64)
+	}
+	if maxQueued.Valid {
+		quota.MaxQueuedTasks = int(maxQueued.Int64)
+	}
+
+	return quota, nil
+}
+
+// SetUserQuota сохраняет персональные лимиты пользователя
+func (e *ExpressionModel) SetUserQuota(userID int, override models.QuotaOverride) error {
+	for _, limit := range []*int{override.MaxActiveExpressions, override.MaxTasksPerExpression, override.MaxQueuedTasks} {
+		if limit != nil && *limit < 0 {
+			return models.ErrorInvalidQuota
+		}
+	}
+
+	if _, err := e.GetUserByID(userID); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO user_quotas (user_id, max_active_expressions, max_tasks_per_expression, max_queued_tasks)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET
+            max_active_expressions = excluded.max_active_expressions,
+            max_tasks_per_expression = excluded.max_tasks_per_expression,
+            max_queued_tasks = excluded.max_queued_tasks
+    `
+	_, err := e.DB.Exec(
+		query,
+		userID,
+		nullableInt(override.MaxActiveExpressions),
+		nullableInt(override.MaxTasksPerExpression),
+		nullableInt(override.MaxQueuedTasks),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set quota for user ID-%d: %v", userID, err)
+	}
+
+	return nil
+}
+
+// GetQuotaUsage считает незавершенные выражения пользователя и его таски, которые еще не посчитаны
+func (e *ExpressionModel) GetQuotaUsage(userID int) (models.QuotaUsage, error) {
+	return quotaUsage(e.DB, userID)
+}
+
+// quotaUsage считает потребление пользователя через db или транзакцию. К непосчитанным таскам
+// добавляются зарезервированные: выражение уже принято, но его таски еще не записаны.
+// Пока таски записываются, уже записанные вычитаются из резерва, чтобы не считать их дважды
+func quotaUsage(q dbExecutor, userID int) (models.QuotaUsage, error) {
+	var (
+		usage    models.QuotaUsage
+		reserved int
+	)
+
+	err := q.QueryRow(`
+        SELECT COUNT(*), COALESCE(SUM(CASE WHEN reserved_tasks > inserted_tasks THEN reserved_tasks - inserted_tasks ELSE 0 END), 0)
+        FROM (
+            SELECT e.reserved_tasks, (SELECT COUNT(*) FROM tasks t WHERE t.expressionID = e.id) AS inserted_tasks
+            FROM expressions e
+            WHERE e.user_id = ? AND e.status IN (?, ?)
+        ) active
+    `,
+		userID,
+		models.StatusWait,
+		models.StatusInProcess,
+	).Scan(&usage.ActiveExpressions, &reserved)
+	if err != nil {
+		return usage, fmt.Errorf("failed to count active expressions: %v", err)
+	}
+
+	err = q.QueryRow(`
+        SELECT COUNT(*)
+        FROM tasks t
+        JOIN expressions e ON t.expressionID = e.id
+        WHERE e.user_id = ? AND t.status IN (?, ?)
+    `,
+		userID,
+		models.StatusWait,
+		models.StatusInProcess,
+	).Scan(&usage.QueuedTasks)
+	if err != nil {
+		return usage, fmt.Errorf("failed to count queued tasks: %v", err)
+	}
+	usage.QueuedTasks += reserved
+
+	return usage, nil
+}
+
+// InsertExpressionWithinQuota сохраняет выражение, если allow разрешает его при текущем потреблении пользователя.
+// Потребление пересчитывается в той же транзакции, что и запись, а строка пользователя блокируется до ее конца,
+// поэтому параллельные запросы одного пользователя, в том числе к разным оркестраторам, проверяются по очереди.
+// reservedTasks тасок выражения учитываются в потреблении, пока не будут записаны или пока не будет вызван ReleaseReservedTasks.
+// Если allow запрещает выражение, возвращает models.ErrorQuotaExceeded
+func (e *ExpressionModel) InsertExpressionWithinQuota(expr *models.Expression, reservedTasks int, allow func(models.QuotaUsage) bool) (int, error) {
+	tx, err := e.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET id = id WHERE id = ?", expr.UserID); err != nil {
+		return 0, fmt.Errorf("failed to lock user ID-%d: %v", expr.UserID, err)
+	}
+
+	usage, err := quotaUsage(tx, expr.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if !allow(usage) {
+		return 0, models.ErrorQuotaExceeded
+	}
+
+	id, err := e.insertExpression(tx, expr)
+	if err != nil {
+		return 0, err
+	}
+
+	if reservedTasks > 0 {
+		if _, err := tx.Exec("UPDATE expressions SET reserved_tasks = ? WHERE id = ?", reservedTasks, id); err != nil {
+			return 0, fmt.Errorf("failed to reserve tasks of expression ID-%d: %v", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit expression: %v", err)
+	}
+
+	return id, nil
+}
+
+// ReleaseReservedTasks снимает резерв тасок выражения, когда они записаны в очередь
+func (e *ExpressionModel) ReleaseReservedTasks(exprID int) error {
+	_, err := e.DB.Exec("UPDATE expressions SET reserved_tasks = 0 WHERE id = ? AND reserved_tasks <> 0", exprID)
+	if err != nil {
+		return fmt.Errorf("failed to release reserved tasks of expression ID-%d: %v", exprID, err)
+	}
+	return nil
+}
+
+func nullableInt(value *int) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
@@ -0,0 +1,113 @@
+package repository_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserQuota_OverridesDefaults(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	userID, _ := repo.CreateUser(&models.User{Login: "testuser", PasswordHash: "hash"})
+	defaults := models.Quota{MaxActiveExpressions: 5, MaxTasksPerExpression: 10, MaxQueuedTasks: 50}
+
+	quota, err := repo.GetUserQuota(userID, defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, quota)
+
+	maxActive := 1
+	err = repo.SetUserQuota(userID, models.QuotaOverride{MaxActiveExpressions: &maxActive})
+	assert.NoError(t, err)
+
+	quota, err = repo.GetUserQuota(userID, defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Quota{MaxActiveExpressions: 1, MaxTasksPerExpression: 10, MaxQueuedTasks: 50}, quota)
+
+	negative := -1
+	assert.Equal(t, models.ErrorInvalidQuota, repo.SetUserQuota(userID, models.QuotaOverride{MaxQueuedTasks: &negative}))
+	assert.Equal(t, models.ErrorUserNotFound, repo.SetUserQuota(999, models.QuotaOverride{}))
+}
+
+func TestGetQuotaUsage(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	activeID, _ := repo.Insert("1+1", 1)
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: activeID, Operation: "+", Status: models.StatusWait})
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: activeID, Operation: "+", Status: models.StatusInProcess})
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: activeID, Operation: "+", Status: models.StatusResolved})
+
+	doneID, _ := repo.Insert("2+2", 1)
+	repo.UpdateStatus(doneID, models.StatusResolved)
+
+	_, _ = repo.Insert("3+3", 2)
+
+	usage, err := repo.GetQuotaUsage(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.ActiveExpressions)
+	assert.Equal(t, 2, usage.QueuedTasks)
+}
+
+func TestInsertExpressionWithinQuota_Concurrent(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	userID, _ := repo.CreateUser(&models.User{Login: "testuser", PasswordHash: "hash"})
+	allow := func(usage models.QuotaUsage) bool {
+		return usage.ActiveExpressions < 3 && usage.QueuedTasks+2 <= 100
+	}
+
+	var (
+		wg       sync.WaitGroup
+		accepted atomic.Int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.InsertExpressionWithinQuota(&models.Expression{UserID: userID, Expression: "1+1+1"}, 2, allow)
+			if err == nil {
+				accepted.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, models.ErrorQuotaExceeded)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), accepted.Load())
+
+	usage, err := repo.GetQuotaUsage(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, usage.ActiveExpressions)
+	assert.Equal(t, 6, usage.QueuedTasks, "tasks of accepted expressions are reserved until they are queued")
+}
+
+func TestReleaseReservedTasks(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	id, err := repo.InsertExpressionWithinQuota(&models.Expression{UserID: 1, Expression: "1+1+1"}, 2, func(models.QuotaUsage) bool { return true })
+	require.NoError(t, err)
+
+	usage, _ := repo.GetQuotaUsage(1)
+	assert.Equal(t, 2, usage.QueuedTasks, "reservation is counted until tasks are queued")
+
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: id, Operation: "+", Status: models.StatusWait})
+	usage, _ = repo.GetQuotaUsage(1)
+	assert.Equal(t, 2, usage.QueuedTasks, "queued task is not counted twice")
+
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: id, Operation: "+", Status: models.StatusWait})
+	usage, _ = repo.GetQuotaUsage(1)
+	assert.Equal(t, 2, usage.QueuedTasks)
+
+	require.NoError(t, repo.ReleaseReservedTasks(id))
+	usage, _ = repo.GetQuotaUsage(1)
+	assert.Equal(t, 2, usage.QueuedTasks)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import "sync"
+
+// taskCandidate - готовая к выполнению таска, которую можно выдать агенту
+type taskCandidate struct {
+	TaskID int
+	UserID int
+	Weight int
+}
+
+// fairScheduler распределяет таски между пользователями по алгоритму start-time fair queueing.
+// Каждому пользователю соответствует виртуальное время: чем больше тасок пользователя уже выдано
+// и чем меньше его вес, тем дальше оно уходит вперед. Следующей выдается таска пользователя
+// с наименьшим виртуальным временем, поэтому большая очередь одного пользователя не блокирует остальных.
+type fairScheduler struct {
+	mu    sync.Mutex
+	clock float64
+	vtime map[int]float64
+}
+
+func newFairScheduler() *fairScheduler {
+	return &fairScheduler{vtime: make(map[int]float64)}
+}
+
+// pick выбирает кандидата, владельца которого планировщик обслуживал меньше всего.
+// Виртуальное время не меняется: таска может достаться другому агенту, поэтому его сдвигает charge после захвата
+func (s *fairScheduler) pick(candidates []taskCandidate) (taskCandidate, bool) {
+	if len(candidates) == 0 {
+		return taskCandidate{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best := -1
+	var bestStart float64
+
+	for i, c := range candidates {
+		start := s.start(c.UserID)
+		if best == -1 || start < bestStart || (start == bestStart && c.TaskID < candidates[best].TaskID) {
+			best = i
+			bestStart = start
+		}
+	}
+
+	return candidates[best], true
+}
+
+// charge сдвигает виртуальное время владельца таски, которая выдана агенту
+func (s *fairScheduler) charge(c taskCandidate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	weight := c.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	start := s.start(c.UserID)
+	s.clock = start
+	s.vtime[c.UserID] = start + 1/float64(weight)
+
+	// пользователь, чье время отстало от общего, все равно стартует с s.clock, поэтому хранить его незачем.
+	// Так карта не растет с каждым пользователем, который когда-либо ставил выражения
+	for userID, v := range s.vtime {
+		if v <= s.clock {
+			delete(s.vtime, userID)
+		}
+	}
+}
+
+// start возвращает виртуальное время, с которого начнется обслуживание следующей таски пользователя.
+// Пользователь, долго простаивавший без тасок, не копит "кредит" и стартует с текущего времени
+func (s *fairScheduler) start(userID int) float64 {
+	if v, ok := s.vtime[userID]; ok && v > s.clock {
+		return v
+	}
+	return s.clock
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFairScheduler_ChargesOnlyClaimedTasks(t *testing.T) {
+	s := newFairScheduler()
+	candidates := []taskCandidate{
+		{TaskID: 1, UserID: 1, Weight: 1},
+		{TaskID: 2, UserID: 2, Weight: 1},
+	}
+
+	chosen, ok := s.pick(candidates)
+	assert.True(t, ok)
+	assert.Equal(t, 1, chosen.UserID)
+
+	chosen, _ = s.pick(candidates)
+	assert.Equal(t, 1, chosen.UserID, "a lost claim must not cost the user its turn")
+
+	s.charge(chosen)
+	chosen, _ = s.pick(candidates)
+	assert.Equal(t, 2, chosen.UserID)
+
+	s.charge(chosen)
+	chosen, _ = s.pick(candidates)
+	assert.Equal(t, 1, chosen.UserID)
+
+	_, ok = s.pick(nil)
+	assert.False(t, ok)
+}
+
+func TestFairScheduler_ForgetsIdleUsers(t *testing.T) {
+	s := newFairScheduler()
+	for userID := 1; userID <= 100; userID++ {
+		s.charge(taskCandidate{TaskID: userID, UserID: userID, Weight: 1})
+	}
+	assert.Len(t, s.vtime, 100)
+
+	s.charge(taskCandidate{TaskID: 101, UserID: 1, Weight: 1})
+	assert.Len(t, s.vtime, 1, "users who have fallen behind the clock are dropped")
+
+	chosen, _ := s.pick([]taskCandidate{
+		{TaskID: 102, UserID: 1, Weight: 1},
+		{TaskID: 103, UserID: 50, Weight: 1},
+	})
+	assert.Equal(t, 50, chosen.UserID, "a dropped user starts from the current clock")
+}
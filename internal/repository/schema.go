@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// schemaTable - таблица базы. Схема одна для SQLite и Postgres, а типы колонок записаны обозначениями
+// {id}, {bigint}, {float}, {arg}, {bool} и {timestamp}, которые каждая база заменяет на свои типы
+type schemaTable struct {
+	name    string
+	columns []schemaColumn
+
+	// constraints - ограничения таблицы, которые идут после колонок
+	constraints []string
+
+	// indexes - индексы таблицы, создаются сразу после нее
+	indexes []string
+}
+
+// schemaColumn - колонка таблицы
+type schemaColumn struct {
+	name       string
+	definition string
+
+	// late означает, что колонка появилась в схеме позже таблицы. В файлах SQLite, созданных раньше,
+	// такие колонки добавляются при запуске
+	late bool
+}
+
+// sqliteTypes и postgresTypes подставляют в схему типы колонок конкретной базы.
+// Время в обеих базах хранится в миллисекундах unix
+var (
+	sqliteTypes = strings.NewReplacer(
+		"{id}", "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"{bigint}", "INTEGER",
+		"{float}", "FLOAT64",
+		"{arg}", "TEXT",
+		"{bool}", "INTEGER NOT NULL DEFAULT 0",
+		"{timestamp}", "DATETIME",
+	)
+	postgresTypes = strings.NewReplacer(
+		"{id}", "BIGSERIAL PRIMARY KEY",
+		"{bigint}", "BIGINT",
+		"{float}", "DOUBLE PRECISION",
+		"{arg}", "DOUBLE PRECISION",
+		"{bool}", "BOOLEAN NOT NULL DEFAULT FALSE",
+		"{timestamp}", "TIMESTAMP",
+	)
+)
+
+var schema = []schemaTable{
+	{
+		name: "expressions",
+		columns: []schemaColumn{
+			{name: "id", definition: "{id}"},
+			{name: "user_id", definition: "{bigint}"},
+			{name: "expression", definition: "TEXT NOT NULL"},
+			{name: "status", definition: "TEXT NOT NULL"},
+			{name: "result", definition: "{float} DEFAULT 0"},
+			{name: "error_message", definition: "TEXT DEFAULT ''"},
+			{name: "priority", definition: "INTEGER NOT NULL DEFAULT 0", late: true},
+			{name: "deadline", definition: "{bigint}", late: true},
+			{name: "cache_key", definition: "TEXT", late: true},
+			{name: "callback_url", definition: "TEXT", late: true},
+			{name: "created_at", definition: "{bigint}", late: true},
+			{name: "started_at", definition: "{bigint}", late: true},
+			{name: "finished_at", definition: "{bigint}", late: true},
+			{name: "reserved_tasks", definition: "INTEGER NOT NULL DEFAULT 0", late: true},
+		},
+	},
+	{
+		name: "tasks",
+		columns: []schemaColumn{
+			{name: "id", definition: "{id}"},
+			{name: "expressionID", definition: "{bigint} NOT NULL"},
+			{name: "arg1", definition: "{arg} NOT NULL"},
+			{name: "arg2", definition: "{arg} NOT NULL"},
+			{name: "prev_task_id1", definition: "{bigint} DEFAULT 0"},
+			{name: "prev_task_id2", definition: "{bigint} DEFAULT 0"},
+			{name: "operation", definition: "TEXT NOT NULL"},
+			{name: "status", definition: "TEXT"},
+			{name: "result", definition: "{float}"},
+			{name: "error_message", definition: "TEXT DEFAULT ''"},
+			{name: "priority", definition: "INTEGER NOT NULL DEFAULT 0", late: true},
+			{name: "critical_path", definition: "{bigint} NOT NULL DEFAULT 0", late: true},
+			{name: "created_at", definition: "{bigint}", late: true},
+			{name: "agent_id", definition: "TEXT", late: true},
+			{name: "started_at", definition: "{bigint}", late: true},
+			{name: "finished_at", definition: "{bigint}", late: true},
+		},
+		constraints: []string{"FOREIGN KEY(expressionID) REFERENCES expressions(id)"},
+	},
+	{
+		name: "users",
+		columns: []schemaColumn{
+			{name: "id", definition: "{id}"},
+			{name: "login", definition: "TEXT NOT NULL UNIQUE"},
+			{name: "password_hash", definition: "TEXT NOT NULL"},
+			{name: "created_at", definition: "{timestamp} DEFAULT CURRENT_TIMESTAMP"},
+			{name: "role", definition: "TEXT NOT NULL DEFAULT 'user'", late: true},
+			{name: "weight", definition: "INTEGER NOT NULL DEFAULT 1", late: true},
+			{name: "webhook_url", definition: "TEXT NOT NULL DEFAULT ''", late: true},
+		},
+	},
+	{
+		name: "user_quotas",
+		columns: []schemaColumn{
+			{name: "user_id", definition: "{bigint} PRIMARY KEY"},
+			{name: "max_active_expressions", definition: "INTEGER"},
+			{name: "max_tasks_per_expression", definition: "INTEGER"},
+			{name: "max_queued_tasks", definition: "INTEGER"},
+		},
+	},
+	{
+		name: "result_cache",
+		columns: []schemaColumn{
+			{name: "cache_key", definition: "TEXT PRIMARY KEY"},
+			{name: "result", definition: "{float} NOT NULL"},
+			{name: "expires_at", definition: "{bigint} NOT NULL"},
+		},
+	},
+	{
+		name: "agents",
+		columns: []schemaColumn{
+			{name: "id", definition: "TEXT PRIMARY KEY"},
+			{name: "hostname", definition: "TEXT NOT NULL DEFAULT ''"},
+			{name: "version", definition: "TEXT NOT NULL DEFAULT ''"},
+			{name: "workers", definition: "INTEGER NOT NULL DEFAULT 0"},
+			{name: "operations", definition: "TEXT NOT NULL DEFAULT ''"},
+			{name: "registered_at", definition: "{bigint} NOT NULL"},
+			{name: "last_seen", definition: "{bigint} NOT NULL"},
+		},
+	},
+	{
+		name: "task_events",
+		columns: []schemaColumn{
+			{name: "id", definition: "{id}"},
+			{name: "task_id", definition: "{bigint} NOT NULL"},
+			{name: "expression_id", definition: "{bigint} NOT NULL"},
+			{name: "status", definition: "TEXT NOT NULL"},
+			{name: "agent_id", definition: "TEXT"},
+			{name: "error_message", definition: "TEXT NOT NULL DEFAULT ''"},
+			{name: "created_at", definition: "{bigint} NOT NULL"},
+		},
+		indexes: []string{"CREATE INDEX IF NOT EXISTS task_events_expression_id ON task_events (expression_id)"},
+	},
+	{
+		name: "leases",
+		columns: []schemaColumn{
+			{name: "name", definition: "TEXT PRIMARY KEY"},
+			{name: "holder", definition: "TEXT NOT NULL"},
+			{name: "expires_at", definition: "{bigint} NOT NULL"},
+		},
+	},
+	{
+		name: "queue_state",
+		columns: []schemaColumn{
+			{name: "id", definition: "INTEGER PRIMARY KEY CHECK (id = 1)"},
+			{name: "paused", definition: "{bool}"},
+			{name: "draining", definition: "{bool}"},
+			{name: "updated_at", definition: "{bigint} NOT NULL"},
+		},
+	},
+	{
+		name: "webhook_deliveries",
+		columns: []schemaColumn{
+			{name: "id", definition: "{id}"},
+			{name: "expression_id", definition: "{bigint} NOT NULL"},
+			{name: "url", definition: "TEXT NOT NULL"},
+			{name: "payload", definition: "TEXT NOT NULL"},
+			{name: "status", definition: "TEXT NOT NULL"},
+			{name: "attempts", definition: "INTEGER NOT NULL DEFAULT 0"},
+			{name: "response_code", definition: "INTEGER NOT NULL DEFAULT 0"},
+			{name: "last_error", definition: "TEXT NOT NULL DEFAULT ''"},
+			{name: "created_at", definition: "{bigint} NOT NULL"},
+			{name: "next_attempt_at", definition: "{bigint}"},
+			{name: "delivered_at", definition: "{bigint}"},
+		},
+		indexes: []string{"CREATE INDEX IF NOT EXISTS webhook_deliveries_next_attempt_at ON webhook_deliveries (status, next_attempt_at)"},
+	},
+}
+
+// uniqueWebhookDeliveries оставляет по одному уведомлению на выражение и запрещает ставить второе.
+// В базах, созданных раньше, индекс по expression_id был неуникальным, и в них могли остаться дубликаты
+var uniqueWebhookDeliveries = []string{
+	`DELETE FROM webhook_deliveries WHERE id NOT IN (SELECT MIN(id) FROM webhook_deliveries GROUP BY expression_id)`,
+	`DROP INDEX IF EXISTS webhook_deliveries_expression_id`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_expression_id_unique ON webhook_deliveries (expression_id)`,
+}
+
+// create возвращает CREATE TABLE с типами колонок конкретной базы
+func (t schemaTable) create(types *strings.Replacer) string {
+	lines := make([]string, 0, len(t.columns)+len(t.constraints))
+	for _, column := range t.columns {
+		lines = append(lines, column.name+" "+types.Replace(column.definition))
+	}
+	lines = append(lines, t.constraints...)
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", t.name, strings.Join(lines, ",\n\t"))
+}
+
+// lateColumns возвращает колонки, появившиеся в таблице позже, с их типами в конкретной базе
+func (t schemaTable) lateColumns(types *strings.Replacer) map[string]string {
+	columns := make(map[string]string)
+	for _, column := range t.columns {
+		if column.late {
+			columns[column.name] = types.Replace(column.definition)
+		}
+	}
+	return columns
+}
+
+// schemaStatements возвращает все запросы, создающие схему в конкретной базе: таблицы вместе с их индексами
+// и уникальный индекс уведомлений
+func schemaStatements(types *strings.Replacer) []string {
+	var statements []string
+	for _, table := range schema {
+		statements = append(statements, table.create(types))
+		statements = append(statements, table.indexes...)
+	}
+	return append(statements, uniqueWebhookDeliveries...)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// recordTaskEvent записывает в историю смену статуса таски. Если agentID не указан, в событие попадает агент,
+// за которым закреплена таска. История нужна только для отладки,
+// поэтому ошибка записи не прерывает основную операцию, а только попадает в лог
+func (e *ExpressionModel) recordTaskEvent(taskID int, status, agentID, errorMessage string) {
+	_, err := e.DB.Exec(`
+	INSERT INTO task_events (task_id, expression_id, status, agent_id, error_message, created_at)
+	SELECT id, expressionID, ?, COALESCE(?, agent_id), ?, CAST(? AS BIGINT) FROM tasks WHERE id = ?
+	`,
+		status,
+		nullableString(agentID),
+		errorMessage,
+		e.now().UnixMilli(),
+		taskID,
+	)
+	if err != nil {
+		log.Printf("failed to record event for task ID-%d: %v", taskID, err)
+	}
+}
+
+// ListTaskEvents возвращает историю смены статусов всех тасок выражения в хронологическом порядке
+func (e *ExpressionModel) ListTaskEvents(exprID int) ([]models.TaskEvent, error) {
+	rows, err := e.DB.Query(`
+	SELECT id, task_id, expression_id, status, agent_id, error_message, created_at
+	FROM task_events
+	WHERE expression_id = ?
+	ORDER BY created_at, id
+	`, exprID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events of expression ID-%d: %v", exprID, err)
+	}
+	defer rows.Close()
+
+	events := []models.TaskEvent{}
+	for rows.Next() {
+		var (
+			event     models.TaskEvent
+			agentID   sql.NullString
+			createdAt int64
+		)
+		err := rows.Scan(&event.ID, &event.TaskID, &event.ExpressionID, &event.Status, &agentID, &event.ErrorMessage, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task event: %v", err)
+		}
+		event.AgentID = agentID.String
+		event.Timestamp = time.UnixMilli(createdAt)
+
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list events of expression ID-%d: %v", exprID, err)
+	}
+
+	return events, nil
+}
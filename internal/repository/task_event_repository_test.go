@@ -0,0 +1,98 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskEvents(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Now()
+	repo.Clock = func() time.Time { return now }
+
+	exprID, _ := repo.Insert("1+2", 1)
+	taskID, err := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 2, Operation: "+", Status: models.StatusWait})
+	require.NoError(t, err)
+
+	now = now.Add(time.Second)
+	_, _, err = repo.GetTask(models.TaskFilter{AgentID: "agent-1"})
+	require.NoError(t, err)
+
+	now = now.Add(time.Second)
+	requeued, err := repo.RequeueTask(taskID)
+	require.NoError(t, err)
+	require.True(t, requeued)
+
+	now = now.Add(time.Second)
+	_, _, err = repo.GetTask(models.TaskFilter{AgentID: "agent-2"})
+	require.NoError(t, err)
+
+	now = now.Add(time.Second)
+	require.NoError(t, repo.UpdateTaskResult(taskID, 3, ""))
+
+	events, err := repo.ListTaskEvents(exprID)
+	require.NoError(t, err)
+
+	type step struct{ status, agent string }
+	var steps []step
+	for _, event := range events {
+		assert.Equal(t, taskID, event.TaskID)
+		steps = append(steps, step{event.Status, event.AgentID})
+	}
+	assert.Equal(t, []step{
+		{models.StatusNew, ""},
+		{models.StatusWait, ""},
+		{models.StatusInProcess, "agent-1"},
+		{models.StatusWait, ""},
+		{models.StatusInProcess, "agent-2"},
+		{models.StatusResolved, "agent-2"},
+	}, steps)
+	assert.Equal(t, 4*time.Second, events[5].Timestamp.Sub(events[0].Timestamp))
+}
+
+func TestTaskEvents_DeadlineExpiry(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	deadline := time.Now().Add(time.Minute)
+	exprID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1+2", Deadline: &deadline})
+	_, err := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 2, Operation: "+", Status: models.StatusWait})
+	require.NoError(t, err)
+	_, _, err = repo.GetTask(models.TaskFilter{AgentID: "agent-1"})
+	require.NoError(t, err)
+
+	_, err = repo.ExpireExpressions(deadline.Add(time.Second))
+	require.NoError(t, err)
+
+	events, err := repo.ListTaskEvents(exprID)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, models.StatusFailed, events[3].Status)
+	assert.Equal(t, "agent-1", events[3].AgentID)
+	assert.Equal(t, models.ErrorDeadlineExceeded.Error(), events[3].ErrorMessage)
+}
+
+func TestTaskEvents_FailedResult(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	exprID, _ := repo.Insert("1/0", 1)
+	taskID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Operation: "/", Status: models.StatusWait})
+	_, _, err := repo.GetTask(models.TaskFilter{AgentID: "agent-1"})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateTaskResult(taskID, 0, models.ErrorDivisionByZero.Error()))
+	require.NoError(t, repo.UpdateTaskResult(taskID, 0, models.ErrorDivisionByZero.Error()))
+
+	events, err := repo.ListTaskEvents(exprID)
+	require.NoError(t, err)
+	require.Len(t, events, 4, "repeated result is ignored")
+	assert.Equal(t, models.StatusFailed, events[3].Status)
+	assert.Equal(t, models.ErrorDivisionByZero.Error(), events[3].ErrorMessage)
+}
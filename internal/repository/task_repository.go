@@ -4,18 +4,19 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
 )
 
-// InsertTask записывает мат выражение в таблицу БД
+// InsertTask записывает мат выражение в таблицу БД. Приоритет таска наследует от своего выражения
 func (e *ExpressionModel) InsertTask(task *models.Task) (int, error) {
 	query := `
-        INSERT INTO tasks (expressionID, arg1, arg2, prev_task_id1, prev_task_id2, operation, status, result)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        INSERT INTO tasks (expressionID, arg1, arg2, prev_task_id1, prev_task_id2, operation, status, result, priority, critical_path, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE((SELECT priority FROM expressions WHERE id = ?), 0), ?, ?)
     `
 
-	result, err := e.DB.Exec(
+	id, err := e.insert(
 		query,
 		task.ExpressionID,
 		task.Arg1,
@@ -25,38 +26,204 @@ func (e *ExpressionModel) InsertTask(task *models.Task) (int, error) {
 		task.Operation,
 		task.Status,
 		task.Result,
+		task.ExpressionID,
+		task.CriticalPathMS,
+		e.now().UnixMilli(),
 	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to insert task: %v", err)
+		return 0, fmt.Errorf("failed to insert task: %w", err)
+	}
+
+	// история таски начинается с ее создания, даже если она сразу встает в очередь
+	e.recordTaskEvent(id, models.StatusNew, "", "")
+	if task.Status != models.StatusNew {
+		e.recordTaskEvent(id, task.Status, "", "")
+	}
+	e.NotifyTasksReady()
+	return id, nil
+}
+
+// GetTask забирает из базы таску для агента. Сначала выбираются готовые к выполнению таски
+// с наибольшим эффективным приоритетом: к приоритету выражения прибавляется по единице
+// за каждый AgingInterval ожидания, поэтому низкоприоритетные таски со временем тоже будут выданы.
+// Среди них выбирается пользователь, которого планировщик обслуживал меньше всего с учетом его веса,
+// и ему выдается таска с самым длинным оставшимся критическим путем (при FIFODispatch - самая старая). Таска закрепляется за агентом filter.AgentID.
+// Захват таски атомарный, поэтому несколько оркестраторов на одной базе не выдадут одну таску дважды:
+// если таску успел забрать кто-то другой, выбирается следующая.
+// Пока выдача тасок приостановлена администратором, возвращает models.ErrorQueuePaused
+func (e *ExpressionModel) GetTask(filter models.TaskFilter) (*models.Task, int, error) {
+	state, err := e.GetQueueState()
+	if err != nil {
+		return nil, 0, err
+	}
+	if state.Paused {
+		return nil, 0, models.ErrorQueuePaused
+	}
+
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		chosen, ok, err := e.pickTask(filter)
+		if err != nil || !ok {
+			return nil, 0, err
+		}
+
+		task, err := e.loadTask(chosen.TaskID)
+		if err != nil || task == nil {
+			return nil, 0, err
+		}
+
+		claimed, err := e.claimTask(task.ID, filter.AgentID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if claimed {
+			e.fairScheduler().charge(chosen)
+			return task, task.ID, nil
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// maxClaimAttempts - сколько раз GetTask пытается захватить таску, если ее перехватывают конкуренты
+const maxClaimAttempts = 10
+
+// maxCandidateUsers - сколько пользователей с тасками наибольшего эффективного приоритета рассматривает планировщик.
+// Если таких пользователей больше, первыми рассматриваются те, чьи лучшие таски созданы раньше
+const maxCandidateUsers = 256
+
+// pickTask выбирает таску, которую следует выдать агенту, но не захватывает ее. База сама отбирает у каждого
+// пользователя лучшую готовую таску и оставляет только таски с наибольшим эффективным приоритетом,
+// а планировщик выбирает из них одного пользователя
+func (e *ExpressionModel) pickTask(filter models.TaskFilter) (taskCandidate, bool, error) {
+	order := "effective_priority DESC, critical_path DESC, id"
+	if e.FIFODispatch {
+		order = "effective_priority DESC, id"
+	}
+
+	readyQuery := `
+        SELECT t.id, COALESCE(e.user_id, 0) AS user_id, COALESCE(u.weight, 1) AS weight, t.critical_path,
+               t.priority + (? - COALESCE(t.created_at, ?)) / ? AS effective_priority
+        FROM tasks t
+        LEFT JOIN expressions e ON t.expressionID = e.id
+        LEFT JOIN users u ON e.user_id = u.id
+        LEFT JOIN tasks t1 ON t.prev_task_id1 = t1.id
+        LEFT JOIN tasks t2 ON t.prev_task_id2 = t2.id
+        WHERE t.status = ?
+        AND (t.prev_task_id1 = 0 OR t1.status = ?)
+        AND (t.prev_task_id2 = 0 OR t2.status = ?)
+        AND (e.deadline IS NULL OR e.deadline > ?)
+    `
+
+	now := e.now().UnixMilli()
+	args := []interface{}{
+		now,
+		now,
+		e.agingInterval().Milliseconds(),
+		models.StatusWait,
+		models.StatusResolved,
+		models.StatusResolved,
+		now,
 	}
 
-	id, err := result.LastInsertId()
+	if len(filter.Operations) > 0 {
+		readyQuery += "        AND t.operation IN (?" + strings.Repeat(", ?", len(filter.Operations)-1) + ")\n"
+		for _, operation := range filter.Operations {
+			args = append(args, operation)
+		}
+	}
+
+	candidatesQuery := `
+        WITH ready AS (
+            SELECT id, user_id, weight, effective_priority,
+                   ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY ` + order + `) AS user_rank
+            FROM (` + readyQuery + `) ready_tasks
+        )
+        SELECT id, user_id, weight
+        FROM ready
+        WHERE user_rank = 1 AND effective_priority = (SELECT MAX(effective_priority) FROM ready)
+        ORDER BY id
+        LIMIT ?
+    `
+	args = append(args, maxCandidateUsers)
+
+	rows, err := e.DB.Query(candidatesQuery, args...)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get task ID: %v", err)
+		return taskCandidate{}, false, fmt.Errorf("failed to get task: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []taskCandidate
+	for rows.Next() {
+		var c taskCandidate
+		if err := rows.Scan(&c.TaskID, &c.UserID, &c.Weight); err != nil {
+			return taskCandidate{}, false, fmt.Errorf("failed to get task: %v", err)
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return taskCandidate{}, false, fmt.Errorf("failed to get task: %v", err)
 	}
+	rows.Close()
 
-	return int(id), nil
+	chosen, ok := e.fairScheduler().pick(candidates)
+	return chosen, ok, nil
 }
 
-// GetTask забирает из базы таску для агента
-func (e *ExpressionModel) GetTask() (*models.Task, int, error) {
+// claimTask закрепляет таску за агентом одним условным UPDATE. Таска захватывается, только если она
+// все еще ждет выполнения; false означает, что ее уже забрал другой агент или оркестратор
+func (e *ExpressionModel) claimTask(taskID int, agentID string) (bool, error) {
+	now := e.now().UnixMilli()
+	res, err := e.DB.Exec(
+		"UPDATE tasks SET status = ?, agent_id = ?, started_at = ? WHERE id = ? AND status = ?",
+		models.StatusInProcess,
+		nullableString(agentID),
+		now,
+		taskID,
+		models.StatusWait,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update task status: %v", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update task status: %v", err)
+	}
+
+	if affected == 1 {
+		e.recordTaskEvent(taskID, models.StatusInProcess, agentID, "")
+
+		_, err = e.DB.Exec(
+			"UPDATE expressions SET started_at = ? WHERE id = (SELECT expressionID FROM tasks WHERE id = ?) AND started_at IS NULL",
+			now,
+			taskID,
+		)
+		if err != nil {
+			log.Printf("failed to mark start of the expression of task ID-%d: %v", taskID, err)
+		}
+	}
+	return affected == 1, nil
+}
+
+// loadTask читает таску вместе с результатами таск, от которых она зависит.
+// Аргументы готовой таски уже не меняются, поэтому ее можно прочитать до захвата
+func (e *ExpressionModel) loadTask(taskID int) (*models.Task, error) {
 	query := `
         SELECT t.id, t.expressionID, 
                COALESCE(t1.result, t.arg1) AS arg1, 
                COALESCE(t2.result, t.arg2) AS arg2, 
                t.prev_task_id1, t.prev_task_id2, 
-               t.operation, t.status, t.result
+               t.operation, t.status, t.result, t.priority, e.deadline
         FROM tasks t
+        LEFT JOIN expressions e ON t.expressionID = e.id
         LEFT JOIN tasks t1 ON t.prev_task_id1 = t1.id
         LEFT JOIN tasks t2 ON t.prev_task_id2 = t2.id
-        WHERE t.status = ?
-        AND (t.prev_task_id1 = 0 OR t1.status = ?)
-        AND (t.prev_task_id2 = 0 OR t2.status = ?)
-        LIMIT 1
+        WHERE t.id = ?
     `
 
 	var task models.Task
-	err := e.DB.QueryRow(query, models.StatusWait, models.StatusResolved, models.StatusResolved).Scan(
+	var deadline sql.NullInt64
+	err := e.DB.QueryRow(query, taskID).Scan(
 		&task.ID,
 		&task.ExpressionID,
 		&task.Arg1,
@@ -66,31 +233,31 @@ func (e *ExpressionModel) GetTask() (*models.Task, int, error) {
 		&task.Operation,
 		&task.Status,
 		&task.Result,
+		&task.Priority,
+		&deadline,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, 0, nil
+			return nil, nil
 		}
-		return nil, 0, fmt.Errorf("failed to get task: %v", err)
-	}
-
-	_, err = e.DB.Exec("UPDATE tasks SET status = ? WHERE id = ?", models.StatusInProcess, task.ID)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to update task status: %v", err)
+		return nil, fmt.Errorf("failed to get task: %v", err)
 	}
+	task.Deadline = timeFromMillis(deadline)
 
-	return &task, task.ID, nil
+	return &task, nil
 }
 
 // GetTaskByID возвращает из базы данных соответствующую таску
 func (e *ExpressionModel) GetTaskByID(taskID int) (*models.Task, error) {
 	query := `
-        SELECT id, expressionID, arg1, arg2, prev_task_id1, prev_task_id2, operation, status, result
+        SELECT id, expressionID, arg1, arg2, prev_task_id1, prev_task_id2, operation, status, result, priority,
+               created_at, started_at, finished_at
         FROM tasks
         WHERE id = ?
     `
 
 	var task models.Task
+	var createdAt, startedAt, finishedAt sql.NullInt64
 	err := e.DB.QueryRow(query, taskID).Scan(
 		&task.ID,
 		&task.ExpressionID,
@@ -101,6 +268,10 @@ func (e *ExpressionModel) GetTaskByID(taskID int) (*models.Task, error) {
 		&task.Operation,
 		&task.Status,
 		&task.Result,
+		&task.Priority,
+		&createdAt,
+		&startedAt,
+		&finishedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -108,6 +279,9 @@ func (e *ExpressionModel) GetTaskByID(taskID int) (*models.Task, error) {
 		}
 		return nil, fmt.Errorf("failed to get task: %v", err)
 	}
+	task.CreatedAt = timeFromMillis(createdAt)
+	task.StartedAt = timeFromMillis(startedAt)
+	task.FinishedAt = timeFromMillis(finishedAt)
 
 	return &task, nil
 }
@@ -132,28 +306,73 @@ func (e *ExpressionModel) UpdateTaskStatus(taskID int, status string) {
 	_, err := e.DB.Exec(query, status, taskID)
 	if err != nil {
 		log.Println(err)
+		return
+	}
+
+	e.recordTaskEvent(taskID, status, "", "")
+
+	if status == models.StatusWait || status == models.StatusNew {
+		e.NotifyTasksReady()
 	}
 }
 
-// UpdateTaskResult обновляет результат таски в базе и если все остальные действия выраженрия выполены, пишет окончательный ответ
+// RequeueTask возвращает в очередь таску, выданную агенту, который перестал выходить на связь.
+// Таска, результат которой уже получен или которая снята с очереди, не меняется
+func (e *ExpressionModel) RequeueTask(taskID int) (bool, error) {
+	res, err := e.DB.Exec(
+		"UPDATE tasks SET status = ?, agent_id = NULL, started_at = NULL WHERE id = ? AND status = ?",
+		models.StatusWait,
+		taskID,
+		models.StatusInProcess,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue task ID-%d: %v", taskID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue task ID-%d: %v", taskID, err)
+	}
+
+	if affected > 0 {
+		e.recordTaskEvent(taskID, models.StatusWait, "", "")
+		e.NotifyTasksReady()
+	}
+	return affected > 0, nil
+}
+
+// UpdateTaskResult обновляет результат таски в базе и если все остальные действия выраженрия выполены, пишет окончательный ответ.
+// Принимается только результат таски, которая сейчас выдана агенту: результат снятой с очереди, возвращенной в очередь
+// или уже посчитанной таски игнорируется. Ошибка вычисления попадает в историю как StatusFailed
 func (e *ExpressionModel) UpdateTaskResult(taskID int, result float64, errorMessage string) error {
-	_, err := e.DB.Exec(
-		"UPDATE tasks SET status = ?, result = ?, error_message = ? WHERE id = ?",
+	res, err := e.DB.Exec(
+		"UPDATE tasks SET status = ?, result = ?, error_message = ?, finished_at = ? WHERE id = ? AND status = ?",
 		models.StatusResolved,
 		result,
 		errorMessage,
+		e.now().UnixMilli(),
 		taskID,
+		models.StatusInProcess,
 	)
 	if err != nil {
 		return err
 	}
 
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		log.Printf("result for task ID-%d ignored: task is not being calculated or does not exist", taskID)
+		return nil
+	}
+
 	if errorMessage != "" {
 		log.Printf("update task ID-%d: %v\nerror message: %v", taskID, result, errorMessage)
+		e.recordTaskEvent(taskID, models.StatusFailed, "", errorMessage)
 	} else {
 		log.Printf("update result for task ID-%d: %v", taskID, result)
+		e.recordTaskEvent(taskID, models.StatusResolved, "", "")
 	}
 
+	e.NotifyTasksReady()
+
 	var exprID int
 	err = e.DB.QueryRow("SELECT expressionID FROM tasks WHERE id = ?", taskID).Scan(&exprID)
 	if err != nil {
@@ -187,3 +406,108 @@ func (e *ExpressionModel) UpdateTaskResult(taskID int, result float64, errorMess
 
 	return nil
 }
+
+// QueuedWorkAhead считает по операциям таски других выражений, которые ждут выдачи или уже считаются
+// и которые планировщик выдаст не позже тасок выражения exprID: их эффективный приоритет не ниже, чем у него.
+// Уже взятые в работу таски учитываются целиком
+func (e *ExpressionModel) QueuedWorkAhead(exprID int) (map[string]int, error) {
+	now := e.now().UnixMilli()
+	aging := e.agingInterval().Milliseconds()
+
+	rows, err := e.DB.Query(`
+        SELECT t.operation, COUNT(*)
+        FROM tasks t, expressions target
+        WHERE target.id = ? AND t.expressionID <> target.id
+        AND t.status IN (?, ?)
+        AND t.priority + (? - COALESCE(t.created_at, ?)) / ? >= target.priority + (? - COALESCE(target.created_at, ?)) / ?
+        GROUP BY t.operation
+    `,
+		exprID,
+		models.StatusWait,
+		models.StatusInProcess,
+		now, now, aging,
+		now, now, aging,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count work ahead of expression ID-%d: %v", exprID, err)
+	}
+	defer rows.Close()
+
+	ahead := make(map[string]int)
+	for rows.Next() {
+		var (
+			operation string
+			count     int
+		)
+		if err := rows.Scan(&operation, &count); err != nil {
+			return nil, fmt.Errorf("failed to count work ahead of expression ID-%d: %v", exprID, err)
+		}
+		ahead[operation] = count
+	}
+
+	return ahead, rows.Err()
+}
+
+// ListExpressionTasks возвращает все таски выражения в порядке создания. Если таска, от которой зависит операнд,
+// уже посчитана, вместо операнда подставляется ее результат
+func (e *ExpressionModel) ListExpressionTasks(exprID int) ([]models.Task, error) {
+	query := `
+        SELECT t.id, t.expressionID,
+               CASE WHEN t.prev_task_id1 != 0 AND t1.status = ? THEN t1.result ELSE t.arg1 END AS arg1,
+               CASE WHEN t.prev_task_id2 != 0 AND t2.status = ? THEN t2.result ELSE t.arg2 END AS arg2,
+               t.prev_task_id1, t.prev_task_id2, t.operation, t.status,
+               COALESCE(t.result, 0), COALESCE(t.error_message, ''), t.agent_id, t.priority,
+               t.created_at, t.started_at, t.finished_at
+        FROM tasks t
+        LEFT JOIN tasks t1 ON t.prev_task_id1 = t1.id
+        LEFT JOIN tasks t2 ON t.prev_task_id2 = t2.id
+        WHERE t.expressionID = ?
+        ORDER BY t.id
+    `
+
+	rows, err := e.DB.Query(query, models.StatusResolved, models.StatusResolved, exprID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks of expression ID-%d: %v", exprID, err)
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var (
+			task                             models.Task
+			agentID                          sql.NullString
+			createdAt, startedAt, finishedAt sql.NullInt64
+		)
+		err := rows.Scan(
+			&task.ID,
+			&task.ExpressionID,
+			&task.Arg1,
+			&task.Arg2,
+			&task.PrevTaskID1,
+			&task.PrevTaskID2,
+			&task.Operation,
+			&task.Status,
+			&task.Result,
+			&task.ErrorMessage,
+			&agentID,
+			&task.Priority,
+			&createdAt,
+			&startedAt,
+			&finishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %v", err)
+		}
+		task.AgentID = agentID.String
+		task.CreatedAt = timeFromMillis(createdAt)
+		task.StartedAt = timeFromMillis(startedAt)
+		task.FinishedAt = timeFromMillis(finishedAt)
+
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tasks of expression ID-%d: %v", exprID, err)
+	}
+
+	return tasks, nil
+}
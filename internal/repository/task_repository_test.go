@@ -1,10 +1,16 @@
 package repository_test
 
 import (
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInsertAndGetTask(t *testing.T) {
@@ -39,7 +45,7 @@ func TestUpdateTaskResult_InvalidExpressionID(t *testing.T) {
 	defer teardown()
 
 	_, err := repo.DB.Exec("INSERT INTO tasks (expressionID, arg1, arg2, operation, status) VALUES (?, ?, ?, ?, ?)",
-		999, 1, 2, "+", models.StatusWait)
+		999, 1, 2, "+", models.StatusInProcess)
 	assert.NoError(t, err)
 
 	var taskID int
@@ -66,6 +72,7 @@ func TestUpdateTaskResult(t *testing.T) {
 	}
 
 	taskID, _ := repo.InsertTask(task)
+	_, _, _ = repo.GetTask(models.TaskFilter{})
 
 	err := repo.UpdateTaskResult(taskID, 3.0, "")
 	assert.NoError(t, err)
@@ -94,7 +101,7 @@ func TestGetTask(t *testing.T) {
 	}
 	taskID, _ := repo.InsertTask(task)
 
-	dbTask, dbTaskID, err := repo.GetTask()
+	dbTask, dbTaskID, err := repo.GetTask(models.TaskFilter{})
 	assert.NoError(t, err)
 	assert.Equal(t, taskID, dbTaskID)
 	assert.Equal(t, task.ExpressionID, dbTask.ExpressionID)
@@ -106,12 +113,33 @@ func TestGetTask(t *testing.T) {
 	dbTask, _ = repo.GetTaskByID(taskID)
 	assert.Equal(t, models.StatusInProcess, dbTask.Status)
 
-	dbTask, dbTaskID, err = repo.GetTask()
+	dbTask, dbTaskID, err = repo.GetTask(models.TaskFilter{})
 	assert.NoError(t, err)
 	assert.Nil(t, dbTask)
 	assert.Zero(t, dbTaskID)
 }
 
+func TestGetTask_CriticalPathFirst(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	exprID, _ := repo.Insert("1+1", 1)
+	shortID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait, CriticalPathMS: 100})
+	longID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait, CriticalPathMS: 300})
+
+	_, taskID, err := repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, longID, taskID, "task on the longest remaining path must be dispatched first")
+
+	repo.FIFODispatch = true
+	_, err = repo.RequeueTask(longID)
+	require.NoError(t, err)
+
+	_, taskID, err = repo.GetTask(models.TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, shortID, taskID, "FIFO dispatch must hand out the oldest task")
+}
+
 func TestGetTaskStatus(t *testing.T) {
 	teardown := setupTestDB(t)
 	defer teardown()
@@ -147,6 +175,7 @@ func TestUpdateTaskResult_WithError(t *testing.T) {
 		Status:       models.StatusWait,
 	}
 	taskID, _ := repo.InsertTask(task)
+	_, _, _ = repo.GetTask(models.TaskFilter{})
 
 	err := repo.UpdateTaskResult(taskID, 0, models.ErrorDivisionByZero.Error())
 	assert.NoError(t, err)
@@ -155,3 +184,275 @@ func TestUpdateTaskResult_WithError(t *testing.T) {
 	assert.Equal(t, models.StatusFailed, expr.Status)
 	assert.Equal(t, models.ErrorDivisionByZero.Error(), expr.ErrorMessage)
 }
+
+func TestGetTask_FairBetweenUsers(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	busyExprID, _ := repo.Insert("1+1+...", 1)
+	for i := 0; i < 100; i++ {
+		_, err := repo.InsertTask(&models.Task{
+			ExpressionID: busyExprID,
+			Arg1:         1,
+			Arg2:         1,
+			Operation:    "+",
+			Status:       models.StatusWait,
+		})
+		assert.NoError(t, err)
+	}
+
+	_, _, err := repo.GetTask(models.TaskFilter{})
+	assert.NoError(t, err)
+
+	otherExprID, _ := repo.Insert("2*2", 2)
+	otherTaskID, _ := repo.InsertTask(&models.Task{
+		ExpressionID: otherExprID,
+		Arg1:         2,
+		Arg2:         2,
+		Operation:    "*",
+		Status:       models.StatusWait,
+	})
+
+	served := false
+	for i := 0; i < 2; i++ {
+		task, _, err := repo.GetTask(models.TaskFilter{})
+		assert.NoError(t, err)
+		if task.ID == otherTaskID {
+			served = true
+		}
+	}
+	assert.True(t, served, "second user's task must be served while the first user has a large backlog")
+}
+
+func TestGetTask_WeightedUsers(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	heavyID, _ := repo.CreateUser(&models.User{Login: "heavy", PasswordHash: "hash"})
+	lightID, _ := repo.CreateUser(&models.User{Login: "light", PasswordHash: "hash"})
+	assert.NoError(t, repo.SetUserWeight(heavyID, 3))
+
+	owners := make(map[int]int)
+	for _, userID := range []int{heavyID, lightID} {
+		exprID, _ := repo.Insert("1+1", userID)
+		for i := 0; i < 20; i++ {
+			taskID, _ := repo.InsertTask(&models.Task{
+				ExpressionID: exprID,
+				Arg1:         1,
+				Arg2:         1,
+				Operation:    "+",
+				Status:       models.StatusWait,
+			})
+			owners[taskID] = userID
+		}
+	}
+
+	servedBy := make(map[int]int)
+	for i := 0; i < 16; i++ {
+		task, _, err := repo.GetTask(models.TaskFilter{})
+		assert.NoError(t, err)
+		servedBy[owners[task.ID]]++
+	}
+
+	assert.Equal(t, 12, servedBy[heavyID])
+	assert.Equal(t, 4, servedBy[lightID])
+}
+
+func TestGetTask_PriorityOrder(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	lowID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1+1", Priority: 0})
+	highID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "2+2", Priority: 5})
+
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: lowID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait})
+	highTaskID, _ := repo.InsertTask(&models.Task{ExpressionID: highID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	dbTask, _ := repo.GetTaskByID(highTaskID)
+	assert.Equal(t, 5, dbTask.Priority, "task must inherit the priority of its expression")
+
+	task, _, err := repo.GetTask(models.TaskFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, highTaskID, task.ID)
+}
+
+func TestGetTask_PriorityAging(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Now()
+	repo.Clock = func() time.Time { return now }
+	repo.AgingInterval = time.Second
+
+	lowID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1+1", Priority: 0})
+	lowTaskID, _ := repo.InsertTask(&models.Task{ExpressionID: lowID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait})
+
+	now = now.Add(10 * time.Second)
+
+	highID, _ := repo.InsertExpression(&models.Expression{UserID: 2, Expression: "2+2", Priority: 5})
+	_, _ = repo.InsertTask(&models.Task{ExpressionID: highID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	task, _, err := repo.GetTask(models.TaskFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, lowTaskID, task.ID, "a task that waited long enough must overtake fresh high priority work")
+}
+
+func TestGetTask_FiltersByOperations(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	exprID, _ := repo.Insert("2*3+1", 1)
+	mulID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 3, Operation: "*", Status: models.StatusWait})
+	addID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait})
+
+	task, _, err := repo.GetTask(models.TaskFilter{AgentID: "adder", Operations: []string{"+", "-"}})
+	assert.NoError(t, err)
+	assert.Equal(t, addID, task.ID)
+
+	task, _, err = repo.GetTask(models.TaskFilter{AgentID: "adder", Operations: []string{"+", "-"}})
+	assert.NoError(t, err)
+	assert.Nil(t, task, "agent must not receive operations it can't execute")
+
+	task, _, err = repo.GetTask(models.TaskFilter{AgentID: "multiplier", Operations: []string{"*"}})
+	assert.NoError(t, err)
+	assert.Equal(t, mulID, task.ID)
+}
+
+func TestGetTask_ConcurrentClaimsFromReplicas(t *testing.T) {
+	const (
+		tasksCount = 100
+		replicas   = 4
+		workers    = 8
+	)
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// каждая реплика оркестратора открывает базу своим соединением
+	orchestrators := make([]*repository.ExpressionModel, replicas)
+	for r := range orchestrators {
+		replicaDB, err := repository.InitDB(dbPath)
+		require.NoError(t, err)
+		defer replicaDB.Close()
+		orchestrators[r] = repository.NewExpressionModel(replicaDB)
+	}
+
+	exprID, _ := orchestrators[0].Insert("1+1", 1)
+	for i := 0; i < tasksCount; i++ {
+		_, err := orchestrators[0].InsertTask(&models.Task{ExpressionID: exprID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait})
+		require.NoError(t, err)
+	}
+
+	var (
+		mu      sync.Mutex
+		claimed = make(map[int]string)
+		dupes   []int
+		wg      sync.WaitGroup
+	)
+	deadline := time.Now().Add(30 * time.Second)
+
+	for r, replica := range orchestrators {
+		for w := 0; w < workers; w++ {
+			agentID := fmt.Sprintf("replica-%d-agent-%d", r, w)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for time.Now().Before(deadline) {
+					task, _, err := replica.GetTask(models.TaskFilter{AgentID: agentID})
+					if !assert.NoError(t, err) {
+						return
+					}
+
+					mu.Lock()
+					if task != nil {
+						if _, ok := claimed[task.ID]; ok {
+							dupes = append(dupes, task.ID)
+						}
+						claimed[task.ID] = agentID
+					}
+					done := len(claimed) == tasksCount
+					mu.Unlock()
+
+					if done {
+						return
+					}
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	assert.Empty(t, dupes, "a task must never be handed out twice")
+	assert.Len(t, claimed, tasksCount)
+
+	for taskID, agentID := range claimed {
+		var owner string
+		require.NoError(t, orchestrators[0].DB.QueryRow("SELECT agent_id FROM tasks WHERE id = ?", taskID).Scan(&owner))
+		assert.Equal(t, agentID, owner)
+	}
+}
+
+func TestUpdateTaskResult_RequiresCalculatingTask(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	exprID, _ := repo.Insert("2+2", 1)
+	taskID, _ := repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait})
+
+	require.NoError(t, repo.UpdateTaskResult(taskID, 4, ""))
+	status, _, err := repo.GetTaskStatus(taskID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusWait, status, "result of a queued task is ignored")
+
+	_, _, _ = repo.GetTask(models.TaskFilter{})
+	require.NoError(t, repo.UpdateTaskResult(taskID, 4, ""))
+	require.NoError(t, repo.UpdateTaskResult(taskID, 5, ""))
+
+	dbTask, err := repo.GetTaskByID(taskID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusResolved, dbTask.Status)
+	assert.Equal(t, 4.0, dbTask.Result, "result of an already calculated task is ignored")
+}
+
+func TestGetTask_BestTaskPerUser(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	urgentID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1+1", Priority: 5})
+	shortID, _ := repo.InsertTask(&models.Task{ExpressionID: urgentID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait, CriticalPathMS: 10})
+	longID, _ := repo.InsertTask(&models.Task{ExpressionID: urgentID, Arg1: 1, Arg2: 1, Operation: "+", Status: models.StatusWait, CriticalPathMS: 30})
+
+	otherID, _ := repo.InsertExpression(&models.Expression{UserID: 2, Expression: "2+2"})
+	lowID, _ := repo.InsertTask(&models.Task{ExpressionID: otherID, Arg1: 2, Arg2: 2, Operation: "+", Status: models.StatusWait, CriticalPathMS: 100})
+
+	for _, want := range []int{longID, shortID, lowID} {
+		task, _, err := repo.GetTask(models.TaskFilter{})
+		require.NoError(t, err)
+		require.NotNil(t, task)
+		assert.Equal(t, want, task.ID)
+	}
+}
+
+func TestQueuedWorkAhead(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.Clock = func() time.Time { return now }
+
+	urgentID, _ := repo.InsertExpression(&models.Expression{UserID: 1, Expression: "1*1", Priority: 5})
+	repo.InsertTask(&models.Task{ExpressionID: urgentID, Arg1: 1, Arg2: 1, Operation: "*", Status: models.StatusWait})
+	repo.InsertTask(&models.Task{ExpressionID: urgentID, Arg1: 1, Arg2: 1, Operation: "*", Status: models.StatusWait})
+
+	backgroundID, _ := repo.InsertExpression(&models.Expression{UserID: 2, Expression: "2-2", Priority: -1})
+	repo.InsertTask(&models.Task{ExpressionID: backgroundID, Arg1: 2, Arg2: 2, Operation: "-", Status: models.StatusWait})
+
+	doneID, _ := repo.Insert("3+3", 2)
+	repo.InsertTask(&models.Task{ExpressionID: doneID, Arg1: 3, Arg2: 3, Operation: "+", Status: models.StatusResolved})
+
+	exprID, _ := repo.Insert("4+4", 3)
+	repo.InsertTask(&models.Task{ExpressionID: exprID, Arg1: 4, Arg2: 4, Operation: "+", Status: models.StatusWait})
+
+	ahead, err := repo.QueuedWorkAhead(exprID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"*": 2}, ahead, "lower priority, finished and own tasks are not ahead")
+}
@@ -10,17 +10,12 @@ import (
 
 // CreateUser вносит в БД данного юзера
 func (e *ExpressionModel) CreateUser(user *models.User) (int, error) {
-	result, err := e.DB.Exec("INSERT INTO users (login, password_hash) VALUES (?, ?)", user.Login, user.PasswordHash)
+	id, err := e.insert("INSERT INTO users (login, password_hash) VALUES (?, ?)", user.Login, user.PasswordHash)
 	if err != nil {
-		return 0, fmt.Errorf("%w: %v", models.ErrorCreatingDatabaseRecord, err)
+		return 0, err
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("%w: %v", models.ErrorReceivingID, err)
-	}
-	user.ID = int(id)
-	return int(id), nil
+	user.ID = id
+	return id, nil
 }
 
 // GetUserByLogin возвращает данные юзера по логину
@@ -39,3 +34,70 @@ func (e *ExpressionModel) GetUserByLogin(login string) (*models.User, error) {
 
 	return &user, nil
 }
+
+// GetUserByID возвращает данные юзера по его ID
+func (e *ExpressionModel) GetUserByID(userID int) (*models.User, error) {
+	var user models.User
+	query := `SELECT id, login, password_hash, role, weight FROM users WHERE id = ?`
+	err := e.DB.QueryRow(query, userID).Scan(&user.ID, &user.Login, &user.PasswordHash, &user.Role, &user.Weight)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrorUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListUsers возвращает всех пользователей вместе с их ролями и весами в планировщике
+func (e *ExpressionModel) ListUsers() ([]models.User, error) {
+	rows, err := e.DB.Query(`SELECT id, login, role, weight FROM users ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %v", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Login, &user.Role, &user.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetUserRole назначает пользователю роль
+func (e *ExpressionModel) SetUserRole(userID int, role string) error {
+	return e.updateUser("UPDATE users SET role = ? WHERE id = ?", role, userID)
+}
+
+// SetUserWeight задает вес пользователя при справедливом распределении тасок между агентами
+func (e *ExpressionModel) SetUserWeight(userID int, weight int) error {
+	if weight <= 0 {
+		return models.ErrorInvalidWeight
+	}
+	return e.updateUser("UPDATE users SET weight = ? WHERE id = ?", weight, userID)
+}
+
+func (e *ExpressionModel) updateUser(query string, value interface{}, userID int) error {
+	result, err := e.DB.Exec(query, value, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user ID-%d: %v", userID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user ID-%d: %v", userID, err)
+	}
+	if affected == 0 {
+		return models.ErrorUserNotFound
+	}
+
+	return nil
+}
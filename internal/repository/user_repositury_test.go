@@ -54,3 +54,30 @@ func TestCreateUser_DuplicateLogin(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "UNIQUE constraint failed")
 }
+
+func TestSetUserWeightAndRole(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	userID, err := repo.CreateUser(&models.User{Login: "testuser", PasswordHash: "hash"})
+	assert.NoError(t, err)
+
+	dbUser, err := repo.GetUserByID(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, dbUser.Role)
+	assert.Equal(t, 1, dbUser.Weight)
+
+	assert.NoError(t, repo.SetUserWeight(userID, 5))
+	assert.NoError(t, repo.SetUserRole(userID, models.RoleAdmin))
+
+	dbUser, err = repo.GetUserByID(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, dbUser.Role)
+	assert.Equal(t, 5, dbUser.Weight)
+
+	assert.Equal(t, models.ErrorInvalidWeight, repo.SetUserWeight(userID, 0))
+	assert.Equal(t, models.ErrorUserNotFound, repo.SetUserWeight(999, 2))
+
+	_, err = repo.GetUserByID(999)
+	assert.Equal(t, models.ErrorUserNotFound, err)
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+)
+
+// GetUserWebhook возвращает адрес, на который по умолчанию уходят уведомления о выражениях пользователя
+func (e *ExpressionModel) GetUserWebhook(userID int) (string, error) {
+	var url string
+	err := e.DB.QueryRow("SELECT webhook_url FROM users WHERE id = ?", userID).Scan(&url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", models.ErrorUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get webhook of user ID-%d: %v", userID, err)
+	}
+
+	return url, nil
+}
+
+// SetUserWebhook задает адрес уведомлений пользователя по умолчанию. Пустой url отключает уведомления
+func (e *ExpressionModel) SetUserWebhook(userID int, url string) error {
+	res, err := e.DB.Exec("UPDATE users SET webhook_url = ? WHERE id = ?", url, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set webhook of user ID-%d: %v", userID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to set webhook of user ID-%d: %v", userID, err)
+	}
+	if affected == 0 {
+		return models.ErrorUserNotFound
+	}
+
+	return nil
+}
+
+// enqueueWebhook кладет в исходящую очередь уведомление о завершении выражения, если у выражения
+// или его автора задан адрес уведомлений. На выражение ставится не больше одного уведомления, повторный вызов,
+// например из-за дубликата результата таски, ничего не делает. Само выражение уже записано, поэтому ошибка только попадает в лог
+func (e *ExpressionModel) enqueueWebhook(exprID int) {
+	var (
+		payload    models.WebhookPayload
+		url        string
+		finishedAt sql.NullInt64
+	)
+
+	err := e.DB.QueryRow(`
+	SELECT ex.id, ex.expression, ex.status, ex.result, ex.error_message, ex.finished_at,
+	       COALESCE(NULLIF(ex.callback_url, ''), u.webhook_url, '')
+	FROM expressions ex
+	LEFT JOIN users u ON u.id = ex.user_id
+	WHERE ex.id = ?
+	`, exprID).Scan(
+		&payload.ExpressionID,
+		&payload.Expression,
+		&payload.Status,
+		&payload.Result,
+		&payload.ErrorMessage,
+		&finishedAt,
+		&url,
+	)
+	if err != nil {
+		log.Printf("failed to prepare webhook for expression ID-%d: %v", exprID, err)
+		return
+	}
+	if url == "" {
+		return
+	}
+	payload.FinishedAt = timeFromMillis(finishedAt)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("failed to encode webhook for expression ID-%d: %v", exprID, err)
+		return
+	}
+
+	now := e.now().UnixMilli()
+	_, err = e.DB.Exec(`
+	INSERT INTO webhook_deliveries (expression_id, url, payload, status, created_at, next_attempt_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (expression_id) DO NOTHING
+	`,
+		exprID,
+		url,
+		string(body),
+		models.DeliveryPending,
+		now,
+		now,
+	)
+	if err != nil {
+		log.Printf("failed to enqueue webhook for expression ID-%d: %v", exprID, err)
+	}
+}
+
+// PendingWebhookDeliveries возвращает до limit недоставленных уведомлений, время очередной попытки которых наступило
+func (e *ExpressionModel) PendingWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return e.queryWebhookDeliveries(
+		"WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?",
+		models.DeliveryPending,
+		now.UnixMilli(),
+		limit,
+	)
+}
+
+// ListWebhookDeliveries возвращает все уведомления о выражении вместе с результатами попыток их доставки
+func (e *ExpressionModel) ListWebhookDeliveries(exprID int) ([]models.WebhookDelivery, error) {
+	return e.queryWebhookDeliveries("WHERE expression_id = ? ORDER BY id", exprID)
+}
+
+// MarkWebhookDelivered отмечает уведомление доставленным
+func (e *ExpressionModel) MarkWebhookDelivered(id, responseCode int) error {
+	_, err := e.DB.Exec(`
+	UPDATE webhook_deliveries
+	SET status = ?, attempts = attempts + 1, response_code = ?, last_error = '', next_attempt_at = NULL, delivered_at = ?
+	WHERE id = ?
+	`,
+		models.DeliveryDelivered,
+		responseCode,
+		e.now().UnixMilli(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery ID-%d delivered: %v", id, err)
+	}
+	return nil
+}
+
+// RetryWebhookDelivery записывает неудачную попытку доставки и назначает следующую на nextAttempt.
+// Если nextAttempt не задан, попытки исчерпаны и уведомление считается проваленным
+func (e *ExpressionModel) RetryWebhookDelivery(id, responseCode int, lastError string, nextAttempt *time.Time) error {
+	status := models.DeliveryPending
+	if nextAttempt == nil {
+		status = models.DeliveryFailed
+	}
+
+	_, err := e.DB.Exec(`
+	UPDATE webhook_deliveries
+	SET status = ?, attempts = attempts + 1, response_code = ?, last_error = ?, next_attempt_at = ?
+	WHERE id = ?
+	`,
+		status,
+		responseCode,
+		lastError,
+		nullableTime(nextAttempt),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record attempt of webhook delivery ID-%d: %v", id, err)
+	}
+	return nil
+}
+
+func (e *ExpressionModel) queryWebhookDeliveries(where string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := e.DB.Query(`
+	SELECT id, expression_id, url, payload, status, attempts, response_code, last_error, created_at, next_attempt_at, delivered_at
+	FROM webhook_deliveries
+	`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var (
+			delivery                 models.WebhookDelivery
+			payload                  string
+			createdAt                int64
+			nextAttemptAt, delivered sql.NullInt64
+		)
+		err := rows.Scan(&delivery.ID, &delivery.ExpressionID, &delivery.URL, &payload, &delivery.Status, &delivery.Attempts,
+			&delivery.ResponseCode, &delivery.LastError, &createdAt, &nextAttemptAt, &delivered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		delivery.Payload = json.RawMessage(payload)
+		delivery.CreatedAt = time.UnixMilli(createdAt)
+		delivery.NextAttemptAt = timeFromMillis(nextAttemptAt)
+		delivery.DeliveredAt = timeFromMillis(delivered)
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookOutbox(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	userID, err := repo.CreateUser(&models.User{Login: "hooked", PasswordHash: "hash"})
+	require.NoError(t, err)
+	require.NoError(t, repo.SetUserWebhook(userID, "http://example.com/default"))
+
+	url, err := repo.GetUserWebhook(userID)
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/default", url)
+	assert.ErrorIs(t, repo.SetUserWebhook(userID+1, "http://example.com"), models.ErrorUserNotFound)
+
+	withCallback := &models.Expression{UserID: userID, Expression: "1+1", CallbackURL: "http://example.com/callback"}
+	_, err = repo.InsertExpression(withCallback)
+	require.NoError(t, err)
+	withDefault := &models.Expression{UserID: userID, Expression: "1/0"}
+	_, err = repo.InsertExpression(withDefault)
+	require.NoError(t, err)
+	silent, err := repo.Insert("2+2", userID+1)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateExpressionResult(withCallback.ID, 2, ""))
+	require.NoError(t, repo.UpdateExpressionResult(withDefault.ID, 0, "division by zero"))
+	require.NoError(t, repo.UpdateExpressionResult(silent, 4, ""))
+
+	deliveries, err := repo.ListWebhookDeliveries(withCallback.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "http://example.com/callback", deliveries[0].URL, "callback URL of the expression wins over the user's webhook")
+	assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+
+	var payload models.WebhookPayload
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	assert.Equal(t, withCallback.ID, payload.ExpressionID)
+	assert.Equal(t, models.StatusResolved, payload.Status)
+	assert.Equal(t, 2.0, payload.Result)
+
+	deliveries, err = repo.ListWebhookDeliveries(withDefault.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "http://example.com/default", deliveries[0].URL)
+
+	deliveries, err = repo.ListWebhookDeliveries(silent)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "no webhook is configured for the expression")
+
+	pending, err := repo.PendingWebhookDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	next := time.Now().Add(time.Minute)
+	require.NoError(t, repo.RetryWebhookDelivery(pending[0].ID, 500, "unexpected response status", &next))
+	require.NoError(t, repo.MarkWebhookDelivered(pending[1].ID, 204))
+
+	pending, err = repo.PendingWebhookDeliveries(time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "retry is scheduled for later")
+
+	pending, err = repo.PendingWebhookDeliveries(next, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, 500, pending[0].ResponseCode)
+}
+
+func TestWebhookOutbox_OneDeliveryPerExpression(t *testing.T) {
+	teardown := setupTestDB(t)
+	defer teardown()
+
+	rejected := &models.Expression{UserID: 1, Expression: "2@2", CallbackURL: "http://example.com/callback"}
+	_, err := repo.InsertExpression(rejected)
+	require.NoError(t, err)
+	repo.UpdateStatus(rejected.ID, models.StatusFailed)
+
+	deliveries, err := repo.ListWebhookDeliveries(rejected.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "expression rejected on submission must not be announced")
+
+	expr := &models.Expression{UserID: 1, Expression: "1+1", CallbackURL: "http://example.com/callback"}
+	_, err = repo.InsertExpression(expr)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateExpressionResult(expr.ID, 2, ""))
+	require.NoError(t, repo.UpdateExpressionResult(expr.ID, 2, ""))
+
+	deliveries, err = repo.ListWebhookDeliveries(expr.ID)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+}
+
+func TestInitDB_RemovesDuplicateWebhookDeliveries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	legacy, err := repository.InitDB(path)
+	require.NoError(t, err)
+	_, err = legacy.Exec("DROP INDEX webhook_deliveries_expression_id_unique")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = legacy.Exec(`INSERT INTO webhook_deliveries (expression_id, url, payload, status, created_at)
+			VALUES (1, 'http://example.com', '{}', 'pending', 0)`)
+		require.NoError(t, err)
+	}
+	require.NoError(t, legacy.Close())
+
+	migrated, err := repository.InitDB(path)
+	require.NoError(t, err)
+	defer migrated.Close()
+
+	deliveries, err := repository.NewExpressionModel(migrated).ListWebhookDeliveries(1)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+}
@@ -0,0 +1,247 @@
+// Package simulator проигрывает набор выражений на модели кластера агентов с виртуальными часами.
+// Выражения разбираются настоящим парсером оркестратора, а таски выдаются и принимаются настоящим
+// репозиторием, поэтому изменения планировщика можно оценить, не дожидаясь реальных TIME_*_MS
+package simulator
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/models"
+	orchestrator "github.com/bulbosaur/calculator-with-authorization/internal/orchestrator/service"
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+)
+
+// Config описывает моделируемый кластер
+type Config struct {
+	// Agents - количество агентов, Workers - сколько тасок каждый из них выполняет одновременно
+	Agents  int
+	Workers int
+
+	// Durations - время выполнения каждой операции. Если не задано, берется из конфигурации (duration.TIME_*_MS)
+	Durations map[string]time.Duration
+
+	// FIFODispatch и AgingInterval передаются репозиторию как есть
+	FIFODispatch  bool
+	AgingInterval time.Duration
+}
+
+// Submission - выражение сценария: кто, когда (от начала симуляции) и с каким приоритетом его отправил
+type Submission struct {
+	At         time.Duration
+	UserID     int
+	Priority   int
+	Expression string
+}
+
+// Report - итоги симуляции
+type Report struct {
+	Expressions int
+	Rejected    int
+	Failed      int
+	Tasks       int
+
+	// Makespan - время от начала симуляции до завершения последней таски
+	Makespan time.Duration
+
+	// AvgQueueWait и MaxQueueWait - сколько выражения ждали, пока агент возьмет первую их таску
+	AvgQueueWait time.Duration
+	MaxQueueWait time.Duration
+
+	// AvgLatency - среднее время от отправки выражения до его завершения
+	AvgLatency time.Duration
+
+	// Utilisation - доля времени, которую воркеры были заняты, от 0 до 1
+	Utilisation float64
+}
+
+// running - таска, которую сейчас выполняет воркер
+type running struct {
+	taskID       int
+	result       float64
+	errorMessage string
+	doneAt       time.Time
+}
+
+// Run проигрывает сценарий на базе db, которая должна быть пустой и инициализированной через repository.InitDB
+func Run(db *sql.DB, cfg Config, submissions []Submission) (Report, error) {
+	if cfg.Agents <= 0 || cfg.Workers <= 0 {
+		return Report{}, fmt.Errorf("cluster must have at least one agent with at least one worker")
+	}
+
+	durations := cfg.Durations
+	if durations == nil {
+		durations = orchestrator.OperationDurations()
+	}
+
+	start := time.Unix(0, 0).UTC()
+	now := start
+	repo := &repository.ExpressionModel{
+		DB:            db,
+		Clock:         func() time.Time { return now },
+		FIFODispatch:  cfg.FIFODispatch,
+		AgingInterval: cfg.AgingInterval,
+	}
+
+	queue := append([]Submission(nil), submissions...)
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].At < queue[j].At })
+
+	var (
+		report     Report
+		exprIDs    []int
+		inFlight   = make([][]running, cfg.Agents)
+		busyTime   time.Duration
+		lastFinish = start
+	)
+
+	for {
+		for len(queue) > 0 && !start.Add(queue[0].At).After(now) {
+			id, ok, err := submit(repo, queue[0], durations)
+			if err != nil {
+				return report, err
+			}
+			if ok {
+				exprIDs = append(exprIDs, id)
+			} else {
+				report.Rejected++
+			}
+			queue = queue[1:]
+		}
+
+		for agent := range inFlight {
+			for len(inFlight[agent]) < cfg.Workers {
+				task, taskID, err := repo.GetTask(models.TaskFilter{AgentID: agentID(agent)})
+				if err != nil {
+					return report, err
+				}
+				if task == nil {
+					break
+				}
+
+				result, errorMessage := execute(task)
+				duration := durations[task.Operation]
+				busyTime += duration
+				report.Tasks++
+				inFlight[agent] = append(inFlight[agent], running{
+					taskID:       taskID,
+					result:       result,
+					errorMessage: errorMessage,
+					doneAt:       now.Add(duration),
+				})
+			}
+		}
+
+		agent, next, ok := nextCompletion(inFlight)
+		switch {
+		case ok && (len(queue) == 0 || !start.Add(queue[0].At).Before(inFlight[agent][next].doneAt)):
+			done := inFlight[agent][next]
+			inFlight[agent] = append(inFlight[agent][:next], inFlight[agent][next+1:]...)
+
+			now = done.doneAt
+			lastFinish = now
+			if err := repo.UpdateTaskResult(done.taskID, done.result, done.errorMessage); err != nil {
+				return report, err
+			}
+		case len(queue) > 0:
+			now = start.Add(queue[0].At)
+		default:
+			report.Makespan = lastFinish.Sub(start)
+			if report.Makespan > 0 {
+				report.Utilisation = float64(busyTime) / float64(report.Makespan*time.Duration(cfg.Agents*cfg.Workers))
+			}
+			return report, summarize(repo, exprIDs, &report)
+		}
+	}
+}
+
+// submit записывает выражение и разбивает его на таски так же, как RegHandler. Критические пути тасок
+// считаются по тем же durations, по которым идет виртуальное время. false означает, что выражение не удалось разобрать
+func submit(repo *repository.ExpressionModel, submission Submission, durations map[string]time.Duration) (int, bool, error) {
+	id, err := repo.InsertExpression(&models.Expression{
+		UserID:     submission.UserID,
+		Expression: submission.Expression,
+		Priority:   submission.Priority,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	if _, err := orchestrator.CalcWithDurations(submission.Expression, id, repo, durations); err != nil {
+		repo.UpdateStatus(id, models.StatusFailed)
+		return id, false, nil
+	}
+
+	return id, true, nil
+}
+
+// execute считает таску так же, как агент, но без ожидания
+func execute(task *models.Task) (float64, string) {
+	switch task.Operation {
+	case "+":
+		return task.Arg1 + task.Arg2, ""
+	case "-":
+		return task.Arg1 - task.Arg2, ""
+	case "*":
+		return task.Arg1 * task.Arg2, ""
+	case "/":
+		if task.Arg2 == 0 {
+			return 0, models.ErrorDivisionByZero.Error()
+		}
+		return task.Arg1 / task.Arg2, ""
+	default:
+		return 0, fmt.Sprintf("invalid operation: %s", task.Operation)
+	}
+}
+
+// nextCompletion находит таску, которая завершится раньше всех
+func nextCompletion(inFlight [][]running) (int, int, bool) {
+	agent, next, ok := 0, 0, false
+	for a := range inFlight {
+		for i := range inFlight[a] {
+			if !ok || inFlight[a][i].doneAt.Before(inFlight[agent][next].doneAt) {
+				agent, next, ok = a, i, true
+			}
+		}
+	}
+
+	return agent, next, ok
+}
+
+// summarize считает время ожидания и завершения выражений по отметкам, которые записал репозиторий
+func summarize(repo *repository.ExpressionModel, exprIDs []int, report *Report) error {
+	var totalWait, totalLatency time.Duration
+
+	for _, id := range exprIDs {
+		expr, err := repo.GetExpression(id)
+		if err != nil {
+			return err
+		}
+
+		report.Expressions++
+		if expr.Status == models.StatusFailed {
+			report.Failed++
+		}
+
+		if expr.CreatedAt != nil && expr.StartedAt != nil {
+			wait := expr.StartedAt.Sub(*expr.CreatedAt)
+			totalWait += wait
+			report.MaxQueueWait = max(report.MaxQueueWait, wait)
+		}
+		if expr.CreatedAt != nil && expr.FinishedAt != nil {
+			totalLatency += expr.FinishedAt.Sub(*expr.CreatedAt)
+		}
+	}
+
+	if report.Expressions > 0 {
+		report.AvgQueueWait = totalWait / time.Duration(report.Expressions)
+		report.AvgLatency = totalLatency / time.Duration(report.Expressions)
+	}
+
+	return nil
+}
+
+func agentID(agent int) string {
+	return fmt.Sprintf("sim-agent-%d", agent+1)
+}
@@ -0,0 +1,120 @@
+package simulator_test
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bulbosaur/calculator-with-authorization/internal/repository"
+	"github.com/bulbosaur/calculator-with-authorization/internal/simulator"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+var durations = map[string]time.Duration{
+	"+": 100 * time.Millisecond,
+	"-": 100 * time.Millisecond,
+	"*": 100 * time.Millisecond,
+	"/": 100 * time.Millisecond,
+}
+
+func run(tb testing.TB, cfg simulator.Config, submissions []simulator.Submission) simulator.Report {
+	tb.Helper()
+
+	logOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(logOutput)
+
+	db, err := repository.InitDB(filepath.Join(tb.TempDir(), "simulation.db"))
+	require.NoError(tb, err)
+	defer db.Close()
+
+	report, err := simulator.Run(db, cfg, submissions)
+	require.NoError(tb, err)
+	return report
+}
+
+func TestRun(t *testing.T) {
+	report := run(t, simulator.Config{Agents: 1, Workers: 1, Durations: durations}, []simulator.Submission{
+		{UserID: 1, Expression: "1+1"},
+		{UserID: 1, Expression: "2+2"},
+		{UserID: 2, Expression: "1/0"},
+		{UserID: 2, Expression: "2@2"},
+	})
+
+	assert.Equal(t, 3, report.Expressions)
+	assert.Equal(t, 1, report.Rejected)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 3, report.Tasks)
+	assert.Equal(t, 300*time.Millisecond, report.Makespan)
+	assert.Equal(t, 100*time.Millisecond, report.AvgQueueWait)
+	assert.Equal(t, 200*time.Millisecond, report.MaxQueueWait)
+	assert.Equal(t, 200*time.Millisecond, report.AvgLatency)
+	assert.InDelta(t, 1.0, report.Utilisation, 1e-9)
+}
+
+func TestRun_DelayedSubmissions(t *testing.T) {
+	report := run(t, simulator.Config{Agents: 2, Workers: 1, Durations: durations}, []simulator.Submission{
+		{At: time.Second, UserID: 1, Expression: "2*3"},
+		{UserID: 1, Expression: "1+1"},
+	})
+
+	assert.Equal(t, 2, report.Expressions)
+	assert.Equal(t, 1100*time.Millisecond, report.Makespan)
+	assert.Zero(t, report.MaxQueueWait)
+	assert.InDelta(t, 200.0/2200.0, report.Utilisation, 1e-9)
+}
+
+func TestRun_EmptyCluster(t *testing.T) {
+	_, err := simulator.Run(nil, simulator.Config{}, nil)
+	assert.Error(t, err)
+}
+
+func TestRun_PlansWithSimulatedDurations(t *testing.T) {
+	previous := viper.Get("duration.TIME_ADDITION_MS")
+	viper.Set("duration.TIME_ADDITION_MS", 5000)
+	defer viper.Set("duration.TIME_ADDITION_MS", previous)
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "simulation.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = simulator.Run(db, simulator.Config{Agents: 1, Workers: 1, Durations: durations}, []simulator.Submission{
+		{UserID: 1, Expression: "1+2+3"},
+	})
+	require.NoError(t, err)
+
+	var longest int64
+	require.NoError(t, db.QueryRow("SELECT MAX(critical_path) FROM tasks").Scan(&longest))
+	assert.Equal(t, int64(200), longest, "critical paths must follow the simulated durations, not the configuration")
+}
+
+// BenchmarkDispatchMakespan сравнивает, за сколько виртуального времени два воркера посчитают
+// очередь выражений при выдаче тасок по критическому пути и в порядке создания
+func BenchmarkDispatchMakespan(b *testing.B) {
+	submissions := []simulator.Submission{
+		{UserID: 1, Expression: "1*1+2*2+3*3+4*4+5*5+6*6"},
+		{UserID: 1, Expression: "1+2+3+4+5+6+7+8+9+10"},
+	}
+
+	for _, tt := range []struct {
+		name string
+		fifo bool
+	}{
+		{name: "FIFO", fifo: true},
+		{name: "CriticalPath", fifo: false},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			var report simulator.Report
+			for i := 0; i < b.N; i++ {
+				report = run(b, simulator.Config{Agents: 1, Workers: 2, Durations: durations, FIFODispatch: tt.fifo}, submissions)
+			}
+			b.ReportMetric(float64(report.Makespan.Milliseconds()), "makespan_ms")
+		})
+	}
+}
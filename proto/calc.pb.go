@@ -32,6 +32,7 @@ type Task struct {
 	Operation     string                 `protobuf:"bytes,7,opt,name=operation,proto3" json:"operation,omitempty"`
 	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
 	Result        float64                `protobuf:"fixed64,9,opt,name=result,proto3" json:"result,omitempty"`
+	TimeoutMs     int64                  `protobuf:"varint,10,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -129,6 +130,13 @@ func (x *Task) GetResult() float64 {
 	return 0
 }
 
+func (x *Task) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
 type Context struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AuthToken     string                 `protobuf:"bytes,1,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
@@ -176,6 +184,8 @@ func (x *Context) GetAuthToken() string {
 type GetTaskRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ctx           *Context               `protobuf:"bytes,1,opt,name=ctx,proto3" json:"ctx,omitempty"`
+	AgentId       string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Operations    []string               `protobuf:"bytes,3,rep,name=operations,proto3" json:"operations,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -217,6 +227,20 @@ func (x *GetTaskRequest) GetCtx() *Context {
 	return nil
 }
 
+func (x *GetTaskRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *GetTaskRequest) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
 type SubmitTaskResultRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TaskId        int32                  `protobuf:"varint,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
@@ -321,73 +345,1022 @@ func (x *SubmitTaskResultResponse) GetSuccess() bool {
 	return false
 }
 
-var File_proto_calc_proto protoreflect.FileDescriptor
+type ReceiveTasksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ctx           *Context               `protobuf:"bytes,1,opt,name=ctx,proto3" json:"ctx,omitempty"`
+	Capacity      int32                  `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	AgentId       string                 `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Operations    []string               `protobuf:"bytes,4,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_calc_proto_rawDesc = "" +
-	"\n" +
-	"\x10proto/calc.proto\x12\x05proto\"\xf8\x01\n" +
-	"\x04Task\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x05R\x02id\x12\"\n" +
-	"\fexpressionId\x18\x02 \x01(\x05R\fexpressionId\x12\x12\n" +
-	"\x04arg1\x18\x03 \x01(\x01R\x04arg1\x12\x12\n" +
-	"\x04arg2\x18\x04 \x01(\x01R\x04arg2\x12\"\n" +
-	"\rprev_task_Id1\x18\x05 \x01(\x05R\vprevTaskId1\x12\"\n" +
-	"\rprev_task_Id2\x18\x06 \x01(\x05R\vprevTaskId2\x12\x1c\n" +
-	"\toperation\x18\a \x01(\tR\toperation\x12\x16\n" +
-	"\x06status\x18\b \x01(\tR\x06status\x12\x16\n" +
-	"\x06result\x18\t \x01(\x01R\x06result\"(\n" +
-	"\aContext\x12\x1d\n" +
-	"\n" +
-	"auth_token\x18\x01 \x01(\tR\tauthToken\"2\n" +
-	"\x0eGetTaskRequest\x12 \n" +
-	"\x03ctx\x18\x01 \x01(\v2\x0e.proto.ContextR\x03ctx\"o\n" +
-	"\x17SubmitTaskResultRequest\x12\x17\n" +
-	"\atask_id\x18\x01 \x01(\x05R\x06taskId\x12\x16\n" +
-	"\x06result\x18\x02 \x01(\x01R\x06result\x12#\n" +
-	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"4\n" +
-	"\x18SubmitTaskResultResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess2\x95\x01\n" +
-	"\vTaskService\x121\n" +
-	"\vReceiveTask\x12\x15.proto.GetTaskRequest\x1a\v.proto.Task\x12S\n" +
-	"\x10SubmitTaskResult\x12\x1e.proto.SubmitTaskResultRequest\x1a\x1f.proto.SubmitTaskResultResponseBBZ@https://github.com/bulbosaur/calculator-with-authorization/protob\x06proto3"
+func (x *ReceiveTasksRequest) Reset() {
+	*x = ReceiveTasksRequest{}
+	mi := &file_proto_calc_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-var (
-	file_proto_calc_proto_rawDescOnce sync.Once
-	file_proto_calc_proto_rawDescData []byte
-)
+func (x *ReceiveTasksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-func file_proto_calc_proto_rawDescGZIP() []byte {
-	file_proto_calc_proto_rawDescOnce.Do(func() {
-		file_proto_calc_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_calc_proto_rawDesc), len(file_proto_calc_proto_rawDesc)))
-	})
-	return file_proto_calc_proto_rawDescData
+func (*ReceiveTasksRequest) ProtoMessage() {}
+
+func (x *ReceiveTasksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-var file_proto_calc_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
-var file_proto_calc_proto_goTypes = []any{
-	(*Task)(nil),                     // 0: proto.Task
-	(*Context)(nil),                  // 1: proto.Context
-	(*GetTaskRequest)(nil),           // 2: proto.GetTaskRequest
-	(*SubmitTaskResultRequest)(nil),  // 3: proto.SubmitTaskResultRequest
-	(*SubmitTaskResultResponse)(nil), // 4: proto.SubmitTaskResultResponse
+// Deprecated: Use ReceiveTasksRequest.ProtoReflect.Descriptor instead.
+func (*ReceiveTasksRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{5}
 }
-var file_proto_calc_proto_depIdxs = []int32{
-	1, // 0: proto.GetTaskRequest.ctx:type_name -> proto.Context
-	2, // 1: proto.TaskService.ReceiveTask:input_type -> proto.GetTaskRequest
-	3, // 2: proto.TaskService.SubmitTaskResult:input_type -> proto.SubmitTaskResultRequest
-	0, // 3: proto.TaskService.ReceiveTask:output_type -> proto.Task
-	4, // 4: proto.TaskService.SubmitTaskResult:output_type -> proto.SubmitTaskResultResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+
+func (x *ReceiveTasksRequest) GetCtx() *Context {
+	if x != nil {
+		return x.Ctx
+	}
+	return nil
 }
 
-func init() { file_proto_calc_proto_init() }
-func file_proto_calc_proto_init() {
-	if File_proto_calc_proto != nil {
-		return
+func (x *ReceiveTasksRequest) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *ReceiveTasksRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *ReceiveTasksRequest) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type TaskBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tasks         []*Task                `protobuf:"bytes,1,rep,name=tasks,proto3" json:"tasks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskBatch) Reset() {
+	*x = TaskBatch{}
+	mi := &file_proto_calc_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskBatch) ProtoMessage() {}
+
+func (x *TaskBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskBatch.ProtoReflect.Descriptor instead.
+func (*TaskBatch) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TaskBatch) GetTasks() []*Task {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+type SubmitTaskResultsRequest struct {
+	state         protoimpl.MessageState     `protogen:"open.v1"`
+	Results       []*SubmitTaskResultRequest `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTaskResultsRequest) Reset() {
+	*x = SubmitTaskResultsRequest{}
+	mi := &file_proto_calc_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTaskResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTaskResultsRequest) ProtoMessage() {}
+
+func (x *SubmitTaskResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTaskResultsRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTaskResultsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SubmitTaskResultsRequest) GetResults() []*SubmitTaskResultRequest {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type SubmitTaskResultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      int32                  `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	FailedTaskIds []int32                `protobuf:"varint,2,rep,packed,name=failed_task_ids,json=failedTaskIds,proto3" json:"failed_task_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTaskResultsResponse) Reset() {
+	*x = SubmitTaskResultsResponse{}
+	mi := &file_proto_calc_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTaskResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTaskResultsResponse) ProtoMessage() {}
+
+func (x *SubmitTaskResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTaskResultsResponse.ProtoReflect.Descriptor instead.
+func (*SubmitTaskResultsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SubmitTaskResultsResponse) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *SubmitTaskResultsResponse) GetFailedTaskIds() []int32 {
+	if x != nil {
+		return x.FailedTaskIds
+	}
+	return nil
+}
+
+type AgentHello struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capacity      int32                  `protobuf:"varint,1,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	AgentId       string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Operations    []string               `protobuf:"bytes,3,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentHello) Reset() {
+	*x = AgentHello{}
+	mi := &file_proto_calc_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentHello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentHello) ProtoMessage() {}
+
+func (x *AgentHello) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentHello.ProtoReflect.Descriptor instead.
+func (*AgentHello) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AgentHello) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *AgentHello) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *AgentHello) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type Heartbeat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SentAtMs      int64                  `protobuf:"varint,1,opt,name=sent_at_ms,json=sentAtMs,proto3" json:"sent_at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file_proto_calc_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Heartbeat) GetSentAtMs() int64 {
+	if x != nil {
+		return x.SentAtMs
+	}
+	return 0
+}
+
+type TaskCancellation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        int32                  `protobuf:"varint,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskCancellation) Reset() {
+	*x = TaskCancellation{}
+	mi := &file_proto_calc_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskCancellation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskCancellation) ProtoMessage() {}
+
+func (x *TaskCancellation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskCancellation.ProtoReflect.Descriptor instead.
+func (*TaskCancellation) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TaskCancellation) GetTaskId() int32 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *TaskCancellation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type AgentConfig struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	HeartbeatIntervalMs int64                  `protobuf:"varint,1,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs,proto3" json:"heartbeat_interval_ms,omitempty"`
+	OperationDurationMs map[string]int64       `protobuf:"bytes,2,rep,name=operation_duration_ms,json=operationDurationMs,proto3" json:"operation_duration_ms,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *AgentConfig) Reset() {
+	*x = AgentConfig{}
+	mi := &file_proto_calc_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentConfig) ProtoMessage() {}
+
+func (x *AgentConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentConfig.ProtoReflect.Descriptor instead.
+func (*AgentConfig) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AgentConfig) GetHeartbeatIntervalMs() int64 {
+	if x != nil {
+		return x.HeartbeatIntervalMs
+	}
+	return 0
+}
+
+func (x *AgentConfig) GetOperationDurationMs() map[string]int64 {
+	if x != nil {
+		return x.OperationDurationMs
+	}
+	return nil
+}
+
+type AgentInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Workers       int32                  `protobuf:"varint,4,opt,name=workers,proto3" json:"workers,omitempty"`
+	Operations    []string               `protobuf:"bytes,5,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentInfo) Reset() {
+	*x = AgentInfo{}
+	mi := &file_proto_calc_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentInfo) ProtoMessage() {}
+
+func (x *AgentInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentInfo.ProtoReflect.Descriptor instead.
+func (*AgentInfo) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AgentInfo) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *AgentInfo) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *AgentInfo) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type RegisterAgentResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	HeartbeatIntervalMs int64                  `protobuf:"varint,1,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs,proto3" json:"heartbeat_interval_ms,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *RegisterAgentResponse) Reset() {
+	*x = RegisterAgentResponse{}
+	mi := &file_proto_calc_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterAgentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterAgentResponse) ProtoMessage() {}
+
+func (x *RegisterAgentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterAgentResponse.ProtoReflect.Descriptor instead.
+func (*RegisterAgentResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RegisterAgentResponse) GetHeartbeatIntervalMs() int64 {
+	if x != nil {
+		return x.HeartbeatIntervalMs
+	}
+	return 0
+}
+
+type AgentHeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentHeartbeatRequest) Reset() {
+	*x = AgentHeartbeatRequest{}
+	mi := &file_proto_calc_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentHeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentHeartbeatRequest) ProtoMessage() {}
+
+func (x *AgentHeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentHeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*AgentHeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *AgentHeartbeatRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+type AgentHeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentHeartbeatResponse) Reset() {
+	*x = AgentHeartbeatResponse{}
+	mi := &file_proto_calc_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentHeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentHeartbeatResponse) ProtoMessage() {}
+
+func (x *AgentHeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentHeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*AgentHeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{16}
+}
+
+type AgentMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*AgentMessage_Hello
+	//	*AgentMessage_Heartbeat
+	//	*AgentMessage_Result
+	Payload       isAgentMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentMessage) Reset() {
+	*x = AgentMessage{}
+	mi := &file_proto_calc_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentMessage) ProtoMessage() {}
+
+func (x *AgentMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentMessage.ProtoReflect.Descriptor instead.
+func (*AgentMessage) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *AgentMessage) GetPayload() isAgentMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetHello() *AgentHello {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetHeartbeat() *Heartbeat {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetResult() *SubmitTaskResultRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isAgentMessage_Payload interface {
+	isAgentMessage_Payload()
+}
+
+type AgentMessage_Hello struct {
+	Hello *AgentHello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type AgentMessage_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,2,opt,name=heartbeat,proto3,oneof"`
+}
+
+type AgentMessage_Result struct {
+	Result *SubmitTaskResultRequest `protobuf:"bytes,3,opt,name=result,proto3,oneof"`
+}
+
+func (*AgentMessage_Hello) isAgentMessage_Payload() {}
+
+func (*AgentMessage_Heartbeat) isAgentMessage_Payload() {}
+
+func (*AgentMessage_Result) isAgentMessage_Payload() {}
+
+type OrchestratorMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*OrchestratorMessage_Assignment
+	//	*OrchestratorMessage_Heartbeat
+	//	*OrchestratorMessage_Cancellation
+	//	*OrchestratorMessage_Config
+	Payload       isOrchestratorMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrchestratorMessage) Reset() {
+	*x = OrchestratorMessage{}
+	mi := &file_proto_calc_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrchestratorMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrchestratorMessage) ProtoMessage() {}
+
+func (x *OrchestratorMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrchestratorMessage.ProtoReflect.Descriptor instead.
+func (*OrchestratorMessage) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *OrchestratorMessage) GetPayload() isOrchestratorMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *OrchestratorMessage) GetAssignment() *Task {
+	if x != nil {
+		if x, ok := x.Payload.(*OrchestratorMessage_Assignment); ok {
+			return x.Assignment
+		}
+	}
+	return nil
+}
+
+func (x *OrchestratorMessage) GetHeartbeat() *Heartbeat {
+	if x != nil {
+		if x, ok := x.Payload.(*OrchestratorMessage_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return nil
+}
+
+func (x *OrchestratorMessage) GetCancellation() *TaskCancellation {
+	if x != nil {
+		if x, ok := x.Payload.(*OrchestratorMessage_Cancellation); ok {
+			return x.Cancellation
+		}
+	}
+	return nil
+}
+
+func (x *OrchestratorMessage) GetConfig() *AgentConfig {
+	if x != nil {
+		if x, ok := x.Payload.(*OrchestratorMessage_Config); ok {
+			return x.Config
+		}
+	}
+	return nil
+}
+
+type isOrchestratorMessage_Payload interface {
+	isOrchestratorMessage_Payload()
+}
+
+type OrchestratorMessage_Assignment struct {
+	Assignment *Task `protobuf:"bytes,1,opt,name=assignment,proto3,oneof"`
+}
+
+type OrchestratorMessage_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,2,opt,name=heartbeat,proto3,oneof"`
+}
+
+type OrchestratorMessage_Cancellation struct {
+	Cancellation *TaskCancellation `protobuf:"bytes,3,opt,name=cancellation,proto3,oneof"`
+}
+
+type OrchestratorMessage_Config struct {
+	Config *AgentConfig `protobuf:"bytes,4,opt,name=config,proto3,oneof"`
+}
+
+func (*OrchestratorMessage_Assignment) isOrchestratorMessage_Payload() {}
+
+func (*OrchestratorMessage_Heartbeat) isOrchestratorMessage_Payload() {}
+
+func (*OrchestratorMessage_Cancellation) isOrchestratorMessage_Payload() {}
+
+func (*OrchestratorMessage_Config) isOrchestratorMessage_Payload() {}
+
+var File_proto_calc_proto protoreflect.FileDescriptor
+
+const file_proto_calc_proto_rawDesc = "" +
+	"\n" +
+	"\x10proto/calc.proto\x12\x05proto\"\x97\x02\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\"\n" +
+	"\fexpressionId\x18\x02 \x01(\x05R\fexpressionId\x12\x12\n" +
+	"\x04arg1\x18\x03 \x01(\x01R\x04arg1\x12\x12\n" +
+	"\x04arg2\x18\x04 \x01(\x01R\x04arg2\x12\"\n" +
+	"\rprev_task_Id1\x18\x05 \x01(\x05R\vprevTaskId1\x12\"\n" +
+	"\rprev_task_Id2\x18\x06 \x01(\x05R\vprevTaskId2\x12\x1c\n" +
+	"\toperation\x18\a \x01(\tR\toperation\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x16\n" +
+	"\x06result\x18\t \x01(\x01R\x06result\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\n" +
+	" \x01(\x03R\ttimeoutMs\"(\n" +
+	"\aContext\x12\x1d\n" +
+	"\n" +
+	"auth_token\x18\x01 \x01(\tR\tauthToken\"m\n" +
+	"\x0eGetTaskRequest\x12 \n" +
+	"\x03ctx\x18\x01 \x01(\v2\x0e.proto.ContextR\x03ctx\x12\x19\n" +
+	"\bagent_id\x18\x02 \x01(\tR\aagentId\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x03 \x03(\tR\n" +
+	"operations\"o\n" +
+	"\x17SubmitTaskResultRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\x05R\x06taskId\x12\x16\n" +
+	"\x06result\x18\x02 \x01(\x01R\x06result\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"4\n" +
+	"\x18SubmitTaskResultResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x8e\x01\n" +
+	"\x13ReceiveTasksRequest\x12 \n" +
+	"\x03ctx\x18\x01 \x01(\v2\x0e.proto.ContextR\x03ctx\x12\x1a\n" +
+	"\bcapacity\x18\x02 \x01(\x05R\bcapacity\x12\x19\n" +
+	"\bagent_id\x18\x03 \x01(\tR\aagentId\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x04 \x03(\tR\n" +
+	"operations\".\n" +
+	"\tTaskBatch\x12!\n" +
+	"\x05tasks\x18\x01 \x03(\v2\v.proto.TaskR\x05tasks\"T\n" +
+	"\x18SubmitTaskResultsRequest\x128\n" +
+	"\aresults\x18\x01 \x03(\v2\x1e.proto.SubmitTaskResultRequestR\aresults\"_\n" +
+	"\x19SubmitTaskResultsResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x05R\baccepted\x12&\n" +
+	"\x0ffailed_task_ids\x18\x02 \x03(\x05R\rfailedTaskIds\"c\n" +
+	"\n" +
+	"AgentHello\x12\x1a\n" +
+	"\bcapacity\x18\x01 \x01(\x05R\bcapacity\x12\x19\n" +
+	"\bagent_id\x18\x02 \x01(\tR\aagentId\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x03 \x03(\tR\n" +
+	"operations\")\n" +
+	"\tHeartbeat\x12\x1c\n" +
+	"\n" +
+	"sent_at_ms\x18\x01 \x01(\x03R\bsentAtMs\"C\n" +
+	"\x10TaskCancellation\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\x05R\x06taskId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\xea\x01\n" +
+	"\vAgentConfig\x122\n" +
+	"\x15heartbeat_interval_ms\x18\x01 \x01(\x03R\x13heartbeatIntervalMs\x12_\n" +
+	"\x15operation_duration_ms\x18\x02 \x03(\v2+.proto.AgentConfig.OperationDurationMsEntryR\x13operationDurationMs\x1aF\n" +
+	"\x18OperationDurationMsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x96\x01\n" +
+	"\tAgentInfo\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x18\n" +
+	"\aworkers\x18\x04 \x01(\x05R\aworkers\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x05 \x03(\tR\n" +
+	"operations\"K\n" +
+	"\x15RegisterAgentResponse\x122\n" +
+	"\x15heartbeat_interval_ms\x18\x01 \x01(\x03R\x13heartbeatIntervalMs\"2\n" +
+	"\x15AgentHeartbeatRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\"\x18\n" +
+	"\x16AgentHeartbeatResponse\"\xb0\x01\n" +
+	"\fAgentMessage\x12)\n" +
+	"\x05hello\x18\x01 \x01(\v2\x11.proto.AgentHelloH\x00R\x05hello\x120\n" +
+	"\theartbeat\x18\x02 \x01(\v2\x10.proto.HeartbeatH\x00R\theartbeat\x128\n" +
+	"\x06result\x18\x03 \x01(\v2\x1e.proto.SubmitTaskResultRequestH\x00R\x06resultB\t\n" +
+	"\apayload\"\xee\x01\n" +
+	"\x13OrchestratorMessage\x12-\n" +
+	"\n" +
+	"assignment\x18\x01 \x01(\v2\v.proto.TaskH\x00R\n" +
+	"assignment\x120\n" +
+	"\theartbeat\x18\x02 \x01(\v2\x10.proto.HeartbeatH\x00R\theartbeat\x12=\n" +
+	"\fcancellation\x18\x03 \x01(\v2\x17.proto.TaskCancellationH\x00R\fcancellation\x12,\n" +
+	"\x06config\x18\x04 \x01(\v2\x12.proto.AgentConfigH\x00R\x06configB\t\n" +
+	"\apayload2\xba\x04\n" +
+	"\vTaskService\x121\n" +
+	"\vReceiveTask\x12\x15.proto.GetTaskRequest\x1a\v.proto.Task\x12S\n" +
+	"\x10SubmitTaskResult\x12\x1e.proto.SubmitTaskResultRequest\x1a\x1f.proto.SubmitTaskResultResponse\x12<\n" +
+	"\fReceiveTasks\x12\x1a.proto.ReceiveTasksRequest\x1a\x10.proto.TaskBatch\x12V\n" +
+	"\x11SubmitTaskResults\x12\x1f.proto.SubmitTaskResultsRequest\x1a .proto.SubmitTaskResultsResponse\x128\n" +
+	"\vStreamTasks\x12\x1a.proto.ReceiveTasksRequest\x1a\v.proto.Task0\x01\x12C\n" +
+	"\fAgentSession\x12\x13.proto.AgentMessage\x1a\x1a.proto.OrchestratorMessage(\x010\x01\x12?\n" +
+	"\rRegisterAgent\x12\x10.proto.AgentInfo\x1a\x1c.proto.RegisterAgentResponse\x12M\n" +
+	"\x0eAgentHeartbeat\x12\x1c.proto.AgentHeartbeatRequest\x1a\x1d.proto.AgentHeartbeatResponseBBZ@https://github.com/bulbosaur/calculator-with-authorization/protob\x06proto3"
+
+var (
+	file_proto_calc_proto_rawDescOnce sync.Once
+	file_proto_calc_proto_rawDescData []byte
+)
+
+func file_proto_calc_proto_rawDescGZIP() []byte {
+	file_proto_calc_proto_rawDescOnce.Do(func() {
+		file_proto_calc_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_calc_proto_rawDesc), len(file_proto_calc_proto_rawDesc)))
+	})
+	return file_proto_calc_proto_rawDescData
+}
+
+var file_proto_calc_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_proto_calc_proto_goTypes = []any{
+	(*Task)(nil),                      // 0: proto.Task
+	(*Context)(nil),                   // 1: proto.Context
+	(*GetTaskRequest)(nil),            // 2: proto.GetTaskRequest
+	(*SubmitTaskResultRequest)(nil),   // 3: proto.SubmitTaskResultRequest
+	(*SubmitTaskResultResponse)(nil),  // 4: proto.SubmitTaskResultResponse
+	(*ReceiveTasksRequest)(nil),       // 5: proto.ReceiveTasksRequest
+	(*TaskBatch)(nil),                 // 6: proto.TaskBatch
+	(*SubmitTaskResultsRequest)(nil),  // 7: proto.SubmitTaskResultsRequest
+	(*SubmitTaskResultsResponse)(nil), // 8: proto.SubmitTaskResultsResponse
+	(*AgentHello)(nil),                // 9: proto.AgentHello
+	(*Heartbeat)(nil),                 // 10: proto.Heartbeat
+	(*TaskCancellation)(nil),          // 11: proto.TaskCancellation
+	(*AgentConfig)(nil),               // 12: proto.AgentConfig
+	(*AgentInfo)(nil),                 // 13: proto.AgentInfo
+	(*RegisterAgentResponse)(nil),     // 14: proto.RegisterAgentResponse
+	(*AgentHeartbeatRequest)(nil),     // 15: proto.AgentHeartbeatRequest
+	(*AgentHeartbeatResponse)(nil),    // 16: proto.AgentHeartbeatResponse
+	(*AgentMessage)(nil),              // 17: proto.AgentMessage
+	(*OrchestratorMessage)(nil),       // 18: proto.OrchestratorMessage
+	nil,                               // 19: proto.AgentConfig.OperationDurationMsEntry
+}
+var file_proto_calc_proto_depIdxs = []int32{
+	1,  // 0: proto.GetTaskRequest.ctx:type_name -> proto.Context
+	1,  // 1: proto.ReceiveTasksRequest.ctx:type_name -> proto.Context
+	0,  // 2: proto.TaskBatch.tasks:type_name -> proto.Task
+	3,  // 3: proto.SubmitTaskResultsRequest.results:type_name -> proto.SubmitTaskResultRequest
+	19, // 4: proto.AgentConfig.operation_duration_ms:type_name -> proto.AgentConfig.OperationDurationMsEntry
+	9,  // 5: proto.AgentMessage.hello:type_name -> proto.AgentHello
+	10, // 6: proto.AgentMessage.heartbeat:type_name -> proto.Heartbeat
+	3,  // 7: proto.AgentMessage.result:type_name -> proto.SubmitTaskResultRequest
+	0,  // 8: proto.OrchestratorMessage.assignment:type_name -> proto.Task
+	10, // 9: proto.OrchestratorMessage.heartbeat:type_name -> proto.Heartbeat
+	11, // 10: proto.OrchestratorMessage.cancellation:type_name -> proto.TaskCancellation
+	12, // 11: proto.OrchestratorMessage.config:type_name -> proto.AgentConfig
+	2,  // 12: proto.TaskService.ReceiveTask:input_type -> proto.GetTaskRequest
+	3,  // 13: proto.TaskService.SubmitTaskResult:input_type -> proto.SubmitTaskResultRequest
+	5,  // 14: proto.TaskService.ReceiveTasks:input_type -> proto.ReceiveTasksRequest
+	7,  // 15: proto.TaskService.SubmitTaskResults:input_type -> proto.SubmitTaskResultsRequest
+	5,  // 16: proto.TaskService.StreamTasks:input_type -> proto.ReceiveTasksRequest
+	17, // 17: proto.TaskService.AgentSession:input_type -> proto.AgentMessage
+	13, // 18: proto.TaskService.RegisterAgent:input_type -> proto.AgentInfo
+	15, // 19: proto.TaskService.AgentHeartbeat:input_type -> proto.AgentHeartbeatRequest
+	0,  // 20: proto.TaskService.ReceiveTask:output_type -> proto.Task
+	4,  // 21: proto.TaskService.SubmitTaskResult:output_type -> proto.SubmitTaskResultResponse
+	6,  // 22: proto.TaskService.ReceiveTasks:output_type -> proto.TaskBatch
+	8,  // 23: proto.TaskService.SubmitTaskResults:output_type -> proto.SubmitTaskResultsResponse
+	0,  // 24: proto.TaskService.StreamTasks:output_type -> proto.Task
+	18, // 25: proto.TaskService.AgentSession:output_type -> proto.OrchestratorMessage
+	14, // 26: proto.TaskService.RegisterAgent:output_type -> proto.RegisterAgentResponse
+	16, // 27: proto.TaskService.AgentHeartbeat:output_type -> proto.AgentHeartbeatResponse
+	20, // [20:28] is the sub-list for method output_type
+	12, // [12:20] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_proto_calc_proto_init() }
+func file_proto_calc_proto_init() {
+	if File_proto_calc_proto != nil {
+		return
+	}
+	file_proto_calc_proto_msgTypes[17].OneofWrappers = []any{
+		(*AgentMessage_Hello)(nil),
+		(*AgentMessage_Heartbeat)(nil),
+		(*AgentMessage_Result)(nil),
+	}
+	file_proto_calc_proto_msgTypes[18].OneofWrappers = []any{
+		(*OrchestratorMessage_Assignment)(nil),
+		(*OrchestratorMessage_Heartbeat)(nil),
+		(*OrchestratorMessage_Cancellation)(nil),
+		(*OrchestratorMessage_Config)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -395,7 +1368,7 @@ func file_proto_calc_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_calc_proto_rawDesc), len(file_proto_calc_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
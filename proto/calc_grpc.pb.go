@@ -19,8 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	TaskService_ReceiveTask_FullMethodName      = "/proto.TaskService/ReceiveTask"
-	TaskService_SubmitTaskResult_FullMethodName = "/proto.TaskService/SubmitTaskResult"
+	TaskService_ReceiveTask_FullMethodName       = "/proto.TaskService/ReceiveTask"
+	TaskService_SubmitTaskResult_FullMethodName  = "/proto.TaskService/SubmitTaskResult"
+	TaskService_ReceiveTasks_FullMethodName      = "/proto.TaskService/ReceiveTasks"
+	TaskService_SubmitTaskResults_FullMethodName = "/proto.TaskService/SubmitTaskResults"
+	TaskService_StreamTasks_FullMethodName       = "/proto.TaskService/StreamTasks"
+	TaskService_AgentSession_FullMethodName      = "/proto.TaskService/AgentSession"
+	TaskService_RegisterAgent_FullMethodName     = "/proto.TaskService/RegisterAgent"
+	TaskService_AgentHeartbeat_FullMethodName    = "/proto.TaskService/AgentHeartbeat"
 )
 
 // TaskServiceClient is the client API for TaskService service.
@@ -29,6 +35,12 @@ const (
 type TaskServiceClient interface {
 	ReceiveTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
 	SubmitTaskResult(ctx context.Context, in *SubmitTaskResultRequest, opts ...grpc.CallOption) (*SubmitTaskResultResponse, error)
+	ReceiveTasks(ctx context.Context, in *ReceiveTasksRequest, opts ...grpc.CallOption) (*TaskBatch, error)
+	SubmitTaskResults(ctx context.Context, in *SubmitTaskResultsRequest, opts ...grpc.CallOption) (*SubmitTaskResultsResponse, error)
+	StreamTasks(ctx context.Context, in *ReceiveTasksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Task], error)
+	AgentSession(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AgentMessage, OrchestratorMessage], error)
+	RegisterAgent(ctx context.Context, in *AgentInfo, opts ...grpc.CallOption) (*RegisterAgentResponse, error)
+	AgentHeartbeat(ctx context.Context, in *AgentHeartbeatRequest, opts ...grpc.CallOption) (*AgentHeartbeatResponse, error)
 }
 
 type taskServiceClient struct {
@@ -59,12 +71,90 @@ func (c *taskServiceClient) SubmitTaskResult(ctx context.Context, in *SubmitTask
 	return out, nil
 }
 
+func (c *taskServiceClient) ReceiveTasks(ctx context.Context, in *ReceiveTasksRequest, opts ...grpc.CallOption) (*TaskBatch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaskBatch)
+	err := c.cc.Invoke(ctx, TaskService_ReceiveTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) SubmitTaskResults(ctx context.Context, in *SubmitTaskResultsRequest, opts ...grpc.CallOption) (*SubmitTaskResultsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitTaskResultsResponse)
+	err := c.cc.Invoke(ctx, TaskService_SubmitTaskResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) StreamTasks(ctx context.Context, in *ReceiveTasksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Task], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[0], TaskService_StreamTasks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReceiveTasksRequest, Task]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TaskService_StreamTasksClient = grpc.ServerStreamingClient[Task]
+
+func (c *taskServiceClient) AgentSession(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AgentMessage, OrchestratorMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[1], TaskService_AgentSession_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AgentMessage, OrchestratorMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TaskService_AgentSessionClient = grpc.BidiStreamingClient[AgentMessage, OrchestratorMessage]
+
+func (c *taskServiceClient) RegisterAgent(ctx context.Context, in *AgentInfo, opts ...grpc.CallOption) (*RegisterAgentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterAgentResponse)
+	err := c.cc.Invoke(ctx, TaskService_RegisterAgent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) AgentHeartbeat(ctx context.Context, in *AgentHeartbeatRequest, opts ...grpc.CallOption) (*AgentHeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentHeartbeatResponse)
+	err := c.cc.Invoke(ctx, TaskService_AgentHeartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TaskServiceServer is the server API for TaskService service.
 // All implementations must embed UnimplementedTaskServiceServer
 // for forward compatibility.
 type TaskServiceServer interface {
 	ReceiveTask(context.Context, *GetTaskRequest) (*Task, error)
 	SubmitTaskResult(context.Context, *SubmitTaskResultRequest) (*SubmitTaskResultResponse, error)
+	ReceiveTasks(context.Context, *ReceiveTasksRequest) (*TaskBatch, error)
+	SubmitTaskResults(context.Context, *SubmitTaskResultsRequest) (*SubmitTaskResultsResponse, error)
+	StreamTasks(*ReceiveTasksRequest, grpc.ServerStreamingServer[Task]) error
+	AgentSession(grpc.BidiStreamingServer[AgentMessage, OrchestratorMessage]) error
+	RegisterAgent(context.Context, *AgentInfo) (*RegisterAgentResponse, error)
+	AgentHeartbeat(context.Context, *AgentHeartbeatRequest) (*AgentHeartbeatResponse, error)
 	mustEmbedUnimplementedTaskServiceServer()
 }
 
@@ -81,6 +171,24 @@ func (UnimplementedTaskServiceServer) ReceiveTask(context.Context, *GetTaskReque
 func (UnimplementedTaskServiceServer) SubmitTaskResult(context.Context, *SubmitTaskResultRequest) (*SubmitTaskResultResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SubmitTaskResult not implemented")
 }
+func (UnimplementedTaskServiceServer) ReceiveTasks(context.Context, *ReceiveTasksRequest) (*TaskBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReceiveTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) SubmitTaskResults(context.Context, *SubmitTaskResultsRequest) (*SubmitTaskResultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitTaskResults not implemented")
+}
+func (UnimplementedTaskServiceServer) StreamTasks(*ReceiveTasksRequest, grpc.ServerStreamingServer[Task]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) AgentSession(grpc.BidiStreamingServer[AgentMessage, OrchestratorMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method AgentSession not implemented")
+}
+func (UnimplementedTaskServiceServer) RegisterAgent(context.Context, *AgentInfo) (*RegisterAgentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterAgent not implemented")
+}
+func (UnimplementedTaskServiceServer) AgentHeartbeat(context.Context, *AgentHeartbeatRequest) (*AgentHeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AgentHeartbeat not implemented")
+}
 func (UnimplementedTaskServiceServer) mustEmbedUnimplementedTaskServiceServer() {}
 func (UnimplementedTaskServiceServer) testEmbeddedByValue()                     {}
 
@@ -138,6 +246,96 @@ func _TaskService_SubmitTaskResult_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TaskService_ReceiveTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReceiveTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ReceiveTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_ReceiveTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ReceiveTasks(ctx, req.(*ReceiveTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_SubmitTaskResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitTaskResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).SubmitTaskResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_SubmitTaskResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).SubmitTaskResults(ctx, req.(*SubmitTaskResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_StreamTasks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReceiveTasksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TaskServiceServer).StreamTasks(m, &grpc.GenericServerStream[ReceiveTasksRequest, Task]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TaskService_StreamTasksServer = grpc.ServerStreamingServer[Task]
+
+func _TaskService_AgentSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TaskServiceServer).AgentSession(&grpc.GenericServerStream[AgentMessage, OrchestratorMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TaskService_AgentSessionServer = grpc.BidiStreamingServer[AgentMessage, OrchestratorMessage]
+
+func _TaskService_RegisterAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).RegisterAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_RegisterAgent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).RegisterAgent(ctx, req.(*AgentInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_AgentHeartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentHeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).AgentHeartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_AgentHeartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).AgentHeartbeat(ctx, req.(*AgentHeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TaskService_ServiceDesc is the grpc.ServiceDesc for TaskService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,7 +351,35 @@ var TaskService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SubmitTaskResult",
 			Handler:    _TaskService_SubmitTaskResult_Handler,
 		},
+		{
+			MethodName: "ReceiveTasks",
+			Handler:    _TaskService_ReceiveTasks_Handler,
+		},
+		{
+			MethodName: "SubmitTaskResults",
+			Handler:    _TaskService_SubmitTaskResults_Handler,
+		},
+		{
+			MethodName: "RegisterAgent",
+			Handler:    _TaskService_RegisterAgent_Handler,
+		},
+		{
+			MethodName: "AgentHeartbeat",
+			Handler:    _TaskService_AgentHeartbeat_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTasks",
+			Handler:       _TaskService_StreamTasks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AgentSession",
+			Handler:       _TaskService_AgentSession_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/calc.proto",
 }